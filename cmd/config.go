@@ -18,19 +18,43 @@ import (
 	"io/ioutil"
 	"time"
 
+	"github.com/palantir/go-baseapp/baseapp"
+	"github.com/palantir/tenablesc-metrics/history"
+	"github.com/palantir/tenablesc-metrics/metrics"
 	"github.com/palantir/tenablesc-metrics/sc"
+	"github.com/palantir/tenablesc-metrics/sink"
 	"github.com/pkg/errors"
 	"gopkg.in/go-playground/validator.v9"
 	"gopkg.in/yaml.v2"
 )
 
 type config struct {
+	// Sinks lists every destination each cycle is written to. When it is empty, the top level datadog, otlp,
+	// influx, graphite and textfile blocks are used instead, with datadog always enabled.
+	Sinks   []sink.Config `yaml:"sinks"`
 	Datadog struct {
 		Address string   `yaml:"address"`
 		Tags    []string `yaml:"tags"`
 	} `yaml:"datadog"`
-	Interval        time.Duration `yaml:"interval"`
-	TenableSCConfig sc.Config     `yaml:"tenablesc"`
+	// OTLP exports each cycle to an OpenTelemetry collector, if an endpoint is set.
+	OTLP sink.OTLPConfig `yaml:"otlp"`
+	// Influx writes each cycle as InfluxDB line protocol, if a file or url is set.
+	Influx sink.InfluxConfig `yaml:"influx"`
+	// Graphite sends each cycle over the Graphite plaintext protocol, if an address is set.
+	Graphite sink.GraphiteConfig `yaml:"graphite"`
+	// Textfile writes a .prom file for node_exporter's textfile collector each cycle, if Directory is set.
+	Textfile struct {
+		Directory string `yaml:"directory"`
+		Name      string `yaml:"name"`
+	} `yaml:"textfile"`
+	// Server is where the serve command exposes Prometheus metrics, unless sinks lists a prometheus sink.
+	Server   baseapp.HTTPConfig `yaml:"server"`
+	Interval time.Duration      `yaml:"interval"`
+	// StaleSeries is the policy for series which disappear between cycles: drop, zero or event.
+	StaleSeries string `yaml:"staleSeries"`
+	// History records every cycle, and the scan results behind it, to a local SQLite database if a path is set.
+	History         history.Config `yaml:"history"`
+	TenableSCConfig sc.Config      `yaml:"tenablesc"`
 	Logging         struct {
 		Level  string `yaml:"level"`
 		Pretty bool   `yaml:"pretty"`
@@ -47,12 +71,45 @@ func parseConfig(bytes []byte) (*config, error) {
 		return nil, errors.New("No tenable URL set")
 	}
 
+	if _, err := c.TenableSCConfig.EnabledCollectors(); err != nil {
+		return nil, errors.Wrapf(err, "invalid collector config")
+	}
+
 	if c.Interval == 0 {
 		c.Interval = 5 * time.Minute
 	}
 
-	if c.Datadog.Address == "" {
-		c.Datadog.Address = "localhost:8125"
+	stalePolicy, err := metrics.ParseStalePolicy(c.StaleSeries)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid staleSeries")
+	}
+	c.StaleSeries = string(stalePolicy)
+
+	if len(c.Sinks) > 0 && c.hasTopLevelSinks() {
+		return nil, errors.New("sinks can't be combined with the top level datadog, otlp, influx, graphite or textfile blocks")
+	}
+	if len(c.Sinks) == 0 {
+		if c.Datadog.Address == "" {
+			c.Datadog.Address = sink.DefaultDatadogAddress
+		}
+		if c.Sinks, err = c.topLevelSinks(); err != nil {
+			return nil, err
+		}
+	}
+	for i := range c.Sinks {
+		if c.Sinks[i].Type == "datadog-api" {
+			c.Sinks[i].SetDefault("interval", c.Interval.String())
+		}
+	}
+	if err := sink.ValidateConfigs(c.Sinks); err != nil {
+		return nil, errors.Wrapf(err, "invalid sinks")
+	}
+
+	if c.Server != (baseapp.HTTPConfig{}) && len(c.sinksOfType("prometheus")) > 0 {
+		return nil, errors.New("server can't be combined with a prometheus sink in sinks")
+	}
+	if c.Server.Port == 0 {
+		c.Server.Port = sink.DefaultPrometheusPort
 	}
 
 	if c.Logging.Level == "" {
@@ -67,6 +124,103 @@ func parseConfig(bytes []byte) (*config, error) {
 	return &c, nil
 }
 
+func (c *config) hasTopLevelSinks() bool {
+	return c.Datadog.Address != "" || len(c.Datadog.Tags) > 0 ||
+		c.OTLP.Endpoint != "" ||
+		c.Influx.File != "" || c.Influx.URL != "" ||
+		c.Graphite.Address != "" ||
+		c.Textfile.Directory != ""
+}
+
+func (c *config) sinksOfType(typ string) []sink.Config {
+	var sinks []sink.Config
+	for _, s := range c.Sinks {
+		if s.Type == typ {
+			sinks = append(sinks, s)
+		}
+	}
+	return sinks
+}
+
+// influxToStdout reports whether an influx sink writes to stdout, where emit --output also writes by default.
+func (c *config) influxToStdout() bool {
+	for _, s := range c.sinksOfType("influx") {
+		var options sink.InfluxConfig
+		if err := s.Decode(&options); err == nil && options.File == "-" {
+			return true
+		}
+	}
+	return false
+}
+
+// prometheusSinks returns the prometheus sinks the serve command runs: those in the sinks list, or else one on server.
+func (c *config) prometheusSinks() ([]sink.Config, error) {
+	if sinks := c.sinksOfType("prometheus"); len(sinks) > 0 {
+		return sinks, nil
+	}
+	s, err := newSinkConfig("prometheus", c.Server)
+	if err != nil {
+		return nil, err
+	}
+	return []sink.Config{s}, nil
+}
+
+// topLevelSinks translates the top level sink blocks, which predate the sinks list, into entries of it.
+func (c *config) topLevelSinks() ([]sink.Config, error) {
+	type entry struct {
+		typ     string
+		options interface{}
+	}
+	entries := []entry{{"datadog", c.Datadog}}
+	if c.OTLP.Endpoint != "" {
+		entries = append(entries, entry{"otlp", c.OTLP})
+	}
+	if c.Influx.File != "" || c.Influx.URL != "" {
+		entries = append(entries, entry{"influx", c.Influx})
+	}
+	if c.Graphite.Address != "" {
+		entries = append(entries, entry{"graphite", c.Graphite})
+	}
+	if c.Textfile.Directory != "" {
+		entries = append(entries, entry{"textfile", map[string]string{"directory": c.Textfile.Directory, "file": c.Textfile.Name}})
+	}
+
+	var sinks []sink.Config
+	for _, e := range entries {
+		sinkConfig, err := newSinkConfig(e.typ, e.options)
+		if err != nil {
+			return nil, err
+		}
+		sinks = append(sinks, sinkConfig)
+	}
+	return sinks, nil
+}
+
+// newSinkConfig builds a sinks list entry of the given type from a struct of its options.
+func newSinkConfig(typ string, options interface{}) (sink.Config, error) {
+	bytes, err := yaml.Marshal(options)
+	if err != nil {
+		return sink.Config{}, err
+	}
+	var raw map[string]interface{}
+	if err := yaml.Unmarshal(bytes, &raw); err != nil {
+		return sink.Config{}, err
+	}
+	if raw == nil {
+		raw = make(map[string]interface{})
+	}
+	raw["type"] = typ
+
+	if bytes, err = yaml.Marshal(raw); err != nil {
+		return sink.Config{}, err
+	}
+	var sinkConfig sink.Config
+	if err := yaml.Unmarshal(bytes, &sinkConfig); err != nil {
+		return sink.Config{}, errors.Wrapf(err, "invalid %s config", typ)
+	}
+	return sinkConfig, nil
+}
+
 func readConfig(cfgFile string) (*config, error) {
 	bytes, err := ioutil.ReadFile(cfgFile)
 	if err != nil {
@@ -0,0 +1,196 @@
+// Copyright 2022 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/palantir/go-baseapp/baseapp"
+	"github.com/palantir/tenablesc-metrics/sink"
+)
+
+func TestParseConfig_topLevelSinks(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name: "datadog by default",
+			want: []string{"datadog"},
+		},
+		{
+			name: "legacy blocks",
+			config: `
+datadog:
+  address: "statsd:8125"
+  tags: ["env:test"]
+textfile:
+  directory: /tmp/textfile
+  name: sc.prom
+graphite:
+  address: "graphite:2003"
+`,
+			want: []string{"datadog", "graphite", "textfile"},
+		},
+		{
+			name: "sinks list",
+			config: `
+sinks:
+  - type: textfile
+    directory: /tmp/textfile
+`,
+			want: []string{"textfile"},
+		},
+		{
+			name: "both styles",
+			config: `
+datadog:
+  address: "statsd:8125"
+sinks:
+  - type: textfile
+    directory: /tmp/textfile
+`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := parseConfig([]byte("tenablesc:\n  url: https://sc.local\n" + tt.config))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			var got []string
+			for _, s := range cfg.Sinks {
+				got = append(got, s.Type)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("sink types = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConfig_topLevelSinksOptions(t *testing.T) {
+	cfg, err := parseConfig([]byte(`
+tenablesc:
+  url: https://sc.local
+datadog:
+  tags: ["env:test"]
+textfile:
+  directory: /tmp/textfile
+  name: sc.prom
+`))
+	if err != nil {
+		t.Fatalf("parseConfig() error = %v", err)
+	}
+
+	var datadog struct {
+		Address string   `yaml:"address"`
+		Tags    []string `yaml:"tags"`
+	}
+	if err := cfg.Sinks[0].Decode(&datadog); err != nil || datadog.Address != sink.DefaultDatadogAddress || !reflect.DeepEqual(datadog.Tags, []string{"env:test"}) {
+		t.Errorf("datadog options = %+v, %v", datadog, err)
+	}
+
+	var textfile struct {
+		Directory string `yaml:"directory"`
+		File      string `yaml:"file"`
+	}
+	if err := cfg.Sinks[1].Decode(&textfile); err != nil || textfile.Directory != "/tmp/textfile" || textfile.File != "sc.prom" {
+		t.Errorf("textfile options = %+v, %v", textfile, err)
+	}
+}
+
+func TestConfig_prometheusSinks(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   string
+		wantPort int
+		wantErr  bool
+	}{
+		{
+			name:     "default server",
+			wantPort: sink.DefaultPrometheusPort,
+		},
+		{
+			name:     "server",
+			config:   "server:\n  port: 9100\n",
+			wantPort: 9100,
+		},
+		{
+			name:     "sinks list",
+			config:   "sinks:\n  - type: prometheus\n    port: 9200\n",
+			wantPort: 9200,
+		},
+		{
+			name:    "both",
+			config:  "server:\n  port: 9100\nsinks:\n  - type: prometheus\n    port: 9200\n",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := parseConfig([]byte("tenablesc:\n  url: https://sc.local\n" + tt.config))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			sinks, err := cfg.prometheusSinks()
+			if err != nil || len(sinks) != 1 {
+				t.Fatalf("prometheusSinks() = %v, %v", sinks, err)
+			}
+			var options baseapp.HTTPConfig
+			if err := sinks[0].Decode(&options); err != nil || options.Port != tt.wantPort {
+				t.Errorf("prometheus options = %+v, %v, want port %d", options, err, tt.wantPort)
+			}
+		})
+	}
+}
+
+func TestConfig_datadogAPIInterval(t *testing.T) {
+	cfg, err := parseConfig([]byte(`
+tenablesc:
+  url: https://sc.local
+interval: 10m
+sinks:
+  - type: datadog-api
+    apiKey: secret
+  - type: datadog-api
+    name: eu
+    apiKey: secret
+    interval: 1m
+`))
+	if err != nil {
+		t.Fatalf("parseConfig() error = %v", err)
+	}
+
+	for i, want := range []time.Duration{10 * time.Minute, time.Minute} {
+		var options sink.DatadogAPIConfig
+		if err := cfg.Sinks[i].Decode(&options); err != nil || options.Interval != want {
+			t.Errorf("sink %d interval = %v, %v, want %v", i, options.Interval, err, want)
+		}
+	}
+}
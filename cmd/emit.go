@@ -15,11 +15,16 @@
 package cmd
 
 import (
+	"io"
+	"os"
+	"strings"
 	"time"
 
-	"github.com/DataDog/datadog-go/v5/statsd"
-	"github.com/palantir/go-baseapp/baseapp/datadog"
+	"github.com/palantir/tenablesc-metrics/history"
 	"github.com/palantir/tenablesc-metrics/metrics"
+	"github.com/palantir/tenablesc-metrics/sc"
+	"github.com/palantir/tenablesc-metrics/sink"
+	"github.com/pkg/errors"
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -37,6 +42,8 @@ func init() {
 	RootCmd.AddCommand(emitMetricsCommand)
 	emitMetricsCommand.Flags().BoolP("once", "", false, "set to emit metrics once and exit.")
 	emitMetricsCommand.Flags().BoolP("dry-run", "n", false, "set to emit only to stdout")
+	emitMetricsCommand.Flags().StringP("output", "o", "", "write each cycle's metrics as "+strings.Join(metrics.OutputFormats, "|"))
+	emitMetricsCommand.Flags().String("output-file", "", "file to write --output to instead of stdout")
 }
 
 const (
@@ -47,24 +54,93 @@ func emitMetrics(cmd *cobra.Command, args []string) error {
 	cmd.SilenceUsage = true
 	once := viper.GetBool("once")
 	dryRun := viper.GetBool("dry-run")
+	output := viper.GetString("output")
+
+	log.Debug().Bool("dryRun", dryRun).Bool("once", once).Str("output", output).Msg("Starting emitMetrics")
+	if output != "" && !metrics.ValidOutputFormat(output) {
+		return errors.Errorf("unknown output format %q, must be one of %s", output, strings.Join(metrics.OutputFormats, ", "))
+	}
+	outputFile := viper.GetString("output-file")
+	if outputFile != "" && output == "" {
+		return errors.New("--output-file needs --output to choose a format")
+	}
 
-	log.Debug().Bool("dryRun", dryRun).Bool("once", once).Msg("Starting emitMetrics")
 	cfg, err := readConfig(viper.GetString("config"))
 	if err != nil {
 		log.Error().Err(err).Msg("failed to parse config")
 		return err
 	}
+	if output != "" && outputFile == "" && !dryRun && cfg.influxToStdout() {
+		return errors.New("--output and the influx sink both write to stdout, set --output-file or an influx file")
+	}
 
-	var emitter *datadog.Emitter
-	var ddClient *statsd.Client
+	var sinks []sink.Sink
+	var store *history.Store
 	if !dryRun {
-		log.Debug().Str("address", cfg.Datadog.Address).Interface("tags", cfg.Datadog.Tags).Msg("setting up datadog config")
-		ddClient, err = statsdClient(cfg.Datadog.Address, cfg.Datadog.Tags)
+		sinks, err = sink.NewAll(cfg.Sinks)
 		if err != nil {
 			return err
 		}
+		defer sink.CloseAll(sinks)
+
+		if store, err = openHistory(cfg); err != nil {
+			return err
+		}
+		if store != nil {
+			defer func() { _ = store.Close() }()
+		}
+	}
+
+	out := io.Writer(os.Stdout)
+	if outputFile != "" {
+		f, err := os.Create(outputFile)
+		if err != nil {
+			return errors.Wrapf(err, "failed to create output file")
+		}
+		defer func() { _ = f.Close() }()
+		out = f
+	}
+
+	return runCycles(cmd, cfg, sinks, cycleOptions{once: once, output: output, out: out, history: store})
+}
+
+type cycleOptions struct {
+	// once stops after the first cycle, returning an error if any sink failed.
+	once bool
+	// output is the format each cycle is also written to out in, if set.
+	output string
+	out    io.Writer
+	// history records each cycle, if set.
+	history *history.Store
+}
+
+// openHistory opens the configured history database, or returns nil if there isn't one.
+func openHistory(cfg *config) (*history.Store, error) {
+	if cfg.History.Path == "" {
+		return nil, nil
+	}
+	store, err := history.Open(cfg.History)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to open history")
+	}
+	return store, nil
+}
+
+// runCycles collects on the configured interval, writing each cycle's snapshot to every sink.
+func runCycles(cmd *cobra.Command, cfg *config, sinks []sink.Sink, opts cycleOptions) error {
+	collection, err := cfg.TenableSCConfig.NewCollection()
+	if err != nil {
+		log.Error().Err(err).Msg("failed to set up collection")
+		return err
 	}
 
+	if opts.history != nil {
+		collection.SetScanResultRecorder(opts.history)
+	}
+
+	tracker := metrics.NewSeriesTracker(metrics.StalePolicy(cfg.StaleSeries))
+	self := metrics.NewSelfMetrics()
+
 	timer := time.NewTicker(cfg.Interval)
 	defer timer.Stop()
 
@@ -73,26 +149,48 @@ func emitMetrics(cmd *cobra.Command, args []string) error {
 	for ; true; <-timer.C {
 		log.Debug().Msg("entering emit loop")
 
-		if !dryRun {
-			metrics.ResetRegistry()
-			emitter = datadog.NewEmitter(ddClient, metrics.GetRegistry())
-		}
-
-		err := updateMetricsRegistry(cfg)
+		snapshot, err := collectSnapshot(collection, tracker, self)
 		if err != nil {
 			log.Error().Err(err).Msg("failed to collect metrics")
 		}
 
-		if !dryRun {
-			emitter.EmitOnce()
-			err := emitter.Flush()
-			if err != nil {
-				return err
+		if opts.history != nil {
+			// History is a record for later analysis; failing to write it shouldn't hold up the sinks.
+			if err := opts.history.RecordSnapshot(cmd.Context(), snapshot); err != nil {
+				log.Error().Err(err).Msg("failed to record history")
+			}
+		}
+
+		if opts.output != "" {
+			if err := metrics.WriteRecords(opts.out, opts.output, snapshot.Metrics()); err != nil {
+				return errors.Wrapf(err, "failed writing output")
+			}
+		} else {
+			for _, m := range snapshot.Metrics() {
+				log.Info().Float64(m.Key(), m.Value).Msg("updating metric")
+			}
+		}
+
+		// A failing sink doesn't stop the others, or the next cycle; how each sink fared is reported next cycle.
+		results := sink.WriteAll(cmd.Context(), sinks, snapshot)
+		var failedSinks []string
+		for _, m := range sink.ResultMetrics(results) {
+			self.Set(m)
+		}
+		for _, r := range results {
+			if r.Err != nil {
+				failedSinks = append(failedSinks, r.Sink)
+			} else if opts.once {
+				// There is no next cycle to report this in, so the log is all there is.
+				log.Info().Str("sink", r.Sink).Dur("duration", r.Duration).Msg("wrote to sink")
 			}
 		}
 
-		if once {
+		if opts.once {
 			log.Info().Msg("Terminating due to once flag")
+			if len(failedSinks) > 0 {
+				return errors.Errorf("failed writing to sinks: %s", strings.Join(failedSinks, ", "))
+			}
 			break
 		}
 		log.Debug().Str("sleepDuration", cfg.Interval.String()).Msg("sleeping between updates")
@@ -101,30 +199,18 @@ func emitMetrics(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
-func statsdClient(address string, tags []string) (*statsd.Client, error) {
-
-	client, err := statsd.New(address, statsd.WithTags(tags))
-	if err != nil {
-		return nil, err
-	}
-
-	return client, nil
+// collectSnapshot runs a collection cycle, and returns its snapshot with removed series and self-metrics folded in.
+func collectSnapshot(collection *sc.Collection, tracker *metrics.SeriesTracker, self *metrics.SelfMetrics) (*metrics.Snapshot, error) {
 
-}
-
-func updateMetricsRegistry(cfg *config) error {
+	// Whatever was collected is emitted even when some collectors failed.
+	snapshot, err := collection.GenerateMetricData()
+	removed := tracker.Track(snapshot.Metrics(), snapshot.Failed())
 
-	metricData, err := cfg.TenableSCConfig.GenerateMetricData()
 	if err != nil {
-		metrics.Increment(failedRunsMetric, 1)
-		return err
+		self.Increment(failedRunsMetric, 1)
+	} else {
+		self.Set(metrics.NewMetric(failedRunsMetric, metrics.Gauge, 0, nil))
 	}
 
-	for k, v := range metricData {
-		log.Info().Int64(k, v).Msg("updating metric")
-		metrics.Update(k, v)
-	}
-
-	metrics.Update(failedRunsMetric, 0)
-	return nil
+	return snapshot.With(removed, self.Metrics()), err
 }
@@ -98,6 +98,10 @@ func setupLogging(cmd *cobra.Command, _ []string) {
 	}
 
 	out := cmd.OutOrStdout()
+	if output := cmd.Flags().Lookup("output"); output != nil && output.Value.String() != "" {
+		// keep stdout clean for the metrics themselves
+		out = cmd.ErrOrStderr()
+	}
 	if cfg.Logging.Pretty {
 		out = zerolog.ConsoleWriter{Out: out}
 	}
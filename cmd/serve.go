@@ -0,0 +1,64 @@
+// Copyright 2022 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"github.com/palantir/tenablesc-metrics/sink"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var serveMetricsCommand = &cobra.Command{
+	Use:    "serve",
+	Short:  "Serve Prometheus Metrics",
+	Long:   "Collect metrics in the background and serve them on /metrics for Prometheus",
+	PreRun: bindSubCmdFlags,
+	RunE:   serveMetrics,
+}
+
+func init() {
+	RootCmd.AddCommand(serveMetricsCommand)
+}
+
+func serveMetrics(cmd *cobra.Command, args []string) error {
+	cmd.SilenceUsage = true
+
+	cfg, err := readConfig(viper.GetString("config"))
+	if err != nil {
+		log.Error().Err(err).Msg("failed to parse config")
+		return err
+	}
+
+	prometheusSinks, err := cfg.prometheusSinks()
+	if err != nil {
+		return err
+	}
+	sinks, err := sink.NewAll(prometheusSinks)
+	if err != nil {
+		return err
+	}
+	defer sink.CloseAll(sinks)
+
+	store, err := openHistory(cfg)
+	if err != nil {
+		return err
+	}
+	if store != nil {
+		defer func() { _ = store.Close() }()
+	}
+
+	return runCycles(cmd, cfg, sinks, cycleOptions{history: store})
+}
@@ -0,0 +1,263 @@
+// Copyright 2022 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package history records each cycle's snapshot, and the scan results behind it, to a local SQLite database.
+// It keeps trends and the inputs to rates across restarts, for as long as the retention allows, without relying on
+// whatever the sinks' backends retain.
+package history
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/palantir/tenablesc-metrics/metrics"
+	"github.com/palantir/tenablesc-metrics/sc"
+
+	// registers the pure Go "sqlite" driver
+	_ "modernc.org/sqlite"
+)
+
+// DefaultRetention keeps a quarter of history.
+const DefaultRetention = 92 * 24 * time.Hour
+
+// Config is where the history database lives and how long rows are kept in it.
+type Config struct {
+	// Path is the SQLite database file; history is only recorded if it is set.
+	Path string `yaml:"path"`
+	// Retention is how long snapshots and scan results are kept; defaults to DefaultRetention.
+	Retention time.Duration `yaml:"retention" validate:"gte=0"`
+}
+
+const schema = `
+CREATE TABLE IF NOT EXISTS snapshots (
+	id             INTEGER PRIMARY KEY,
+	collected_at   INTEGER NOT NULL,
+	failed_sources INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS snapshots_collected_at ON snapshots (collected_at);
+
+CREATE TABLE IF NOT EXISTS samples (
+	snapshot_id INTEGER NOT NULL REFERENCES snapshots (id),
+	name        TEXT NOT NULL,
+	tags        TEXT NOT NULL,
+	series      TEXT NOT NULL,
+	kind        TEXT NOT NULL,
+	unit        TEXT NOT NULL,
+	collector   TEXT NOT NULL,
+	value       REAL NOT NULL,
+	observed_at INTEGER
+);
+CREATE INDEX IF NOT EXISTS samples_snapshot ON samples (snapshot_id);
+CREATE INDEX IF NOT EXISTS samples_name ON samples (name, snapshot_id);
+
+CREATE TABLE IF NOT EXISTS scan_results (
+	org           TEXT NOT NULL,
+	id            TEXT NOT NULL,
+	scan_name     TEXT NOT NULL,
+	status        TEXT NOT NULL,
+	import_status TEXT NOT NULL,
+	start_time    INTEGER,
+	finish_time   INTEGER,
+	scan_duration INTEGER NOT NULL,
+	total_ips     INTEGER NOT NULL,
+	scanned_ips   INTEGER NOT NULL,
+	last_seen     INTEGER NOT NULL,
+	PRIMARY KEY (org, id)
+);
+CREATE INDEX IF NOT EXISTS scan_results_last_seen ON scan_results (last_seen);
+`
+
+// Store is an open history database. It is safe for concurrent use, though writes are serialized.
+type Store struct {
+	db        *sql.DB
+	retention time.Duration
+	now       func() time.Time
+}
+
+// Open opens, creating if needed, the database at cfg.Path.
+func Open(cfg Config) (*Store, error) {
+	if cfg.Retention == 0 {
+		cfg.Retention = DefaultRetention
+	}
+
+	db, err := sql.Open("sqlite", "file:"+cfg.Path+"?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)")
+	if err != nil {
+		return nil, fmt.Errorf("failed opening history database %s: %w", cfg.Path, err)
+	}
+	// SQLite allows a single writer; one connection saves concurrent org jobs from fighting over the lock.
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec(schema); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("failed creating history schema in %s: %w", cfg.Path, err)
+	}
+
+	return &Store{db: db, retention: cfg.Retention, now: time.Now}, nil
+}
+
+// Close closes the database.
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// RecordSnapshot stores every metric of the snapshot, then drops whatever has aged out of the retention.
+func (s *Store) RecordSnapshot(ctx context.Context, snapshot *metrics.Snapshot) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	res, err := tx.ExecContext(ctx, `INSERT INTO snapshots (collected_at, failed_sources) VALUES (?, ?)`,
+		snapshot.Time().Unix(), len(snapshot.Failed()))
+	if err != nil {
+		return fmt.Errorf("failed recording snapshot: %w", err)
+	}
+	snapshotID, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	insert, err := tx.PrepareContext(ctx, `INSERT INTO samples
+		(snapshot_id, name, tags, series, kind, unit, collector, value, observed_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = insert.Close() }()
+
+	for _, m := range snapshot.Metrics() {
+		tags, err := encodeTags(m.Tags)
+		if err != nil {
+			return err
+		}
+		var observedAt interface{}
+		if !m.Timestamp.IsZero() {
+			observedAt = m.Timestamp.Unix()
+		}
+		if _, err := insert.ExecContext(ctx, snapshotID, m.Name, tags, m.Key(), m.Kind.String(), m.Unit, m.Collector, m.Value, observedAt); err != nil {
+			return fmt.Errorf("failed recording %s: %w", m.Key(), err)
+		}
+	}
+
+	if err := s.pruneTx(ctx, tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// RecordScanResults inserts or updates each result, keyed by org and result ID.
+func (s *Store) RecordScanResults(facts []sc.ScanResultFact) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	upsert, err := tx.Prepare(`INSERT INTO scan_results
+		(org, id, scan_name, status, import_status, start_time, finish_time, scan_duration, total_ips, scanned_ips, last_seen)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (org, id) DO UPDATE SET
+			scan_name = excluded.scan_name,
+			status = excluded.status,
+			import_status = excluded.import_status,
+			start_time = excluded.start_time,
+			finish_time = excluded.finish_time,
+			scan_duration = excluded.scan_duration,
+			total_ips = excluded.total_ips,
+			scanned_ips = excluded.scanned_ips,
+			last_seen = excluded.last_seen`)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = upsert.Close() }()
+
+	now := s.now().Unix()
+	for _, f := range facts {
+		if _, err := upsert.Exec(f.Org, f.ID, f.ScanName, f.Status, f.ImportStatus, nullableUnix(f.StartTime), nullableUnix(f.FinishTime),
+			f.ScanDuration, f.TotalIPs, f.ScannedIPs, now); err != nil {
+			return fmt.Errorf("failed recording scan result %s of org %s: %w", f.ID, f.Org, err)
+		}
+	}
+	return tx.Commit()
+}
+
+// Point is one recorded value of a series.
+type Point struct {
+	Time  time.Time
+	Tags  map[string]string
+	Value float64
+}
+
+// Values returns every recorded value of the named metric collected at or after since, oldest first.
+func (s *Store) Values(ctx context.Context, name string, since time.Time) ([]Point, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT snapshots.collected_at, samples.tags, samples.value
+		FROM samples JOIN snapshots ON snapshots.id = samples.snapshot_id
+		WHERE samples.name = ? AND snapshots.collected_at >= ?
+		ORDER BY snapshots.collected_at, samples.series`, name, since.Unix())
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var points []Point
+	for rows.Next() {
+		var collectedAt int64
+		var tags string
+		var p Point
+		if err := rows.Scan(&collectedAt, &tags, &p.Value); err != nil {
+			return nil, err
+		}
+		p.Time = time.Unix(collectedAt, 0)
+		if err := json.Unmarshal([]byte(tags), &p.Tags); err != nil {
+			return nil, err
+		}
+		points = append(points, p)
+	}
+	return points, rows.Err()
+}
+
+// pruneTx deletes snapshots collected, and scan results last seen, before the retention.
+func (s *Store) pruneTx(ctx context.Context, tx *sql.Tx) error {
+	cutoff := s.now().Add(-s.retention).Unix()
+
+	for _, query := range []string{
+		`DELETE FROM samples WHERE snapshot_id IN (SELECT id FROM snapshots WHERE collected_at < ?)`,
+		`DELETE FROM snapshots WHERE collected_at < ?`,
+		`DELETE FROM scan_results WHERE last_seen < ?`,
+	} {
+		if _, err := tx.ExecContext(ctx, query, cutoff); err != nil {
+			return fmt.Errorf("failed pruning history: %w", err)
+		}
+	}
+	return nil
+}
+
+// encodeTags stores tags as a JSON object, so they can be queried with SQLite's json_extract.
+func encodeTags(tags map[string]string) (string, error) {
+	if tags == nil {
+		tags = map[string]string{}
+	}
+	bytes, err := json.Marshal(tags)
+	return string(bytes), err
+}
+
+func nullableUnix(t time.Time) interface{} {
+	if t.IsZero() {
+		return nil
+	}
+	return t.Unix()
+}
@@ -0,0 +1,78 @@
+// Copyright 2022 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package history
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/palantir/tenablesc-metrics/metrics"
+	"github.com/palantir/tenablesc-metrics/sc"
+)
+
+func TestStore(t *testing.T) {
+	ctx := context.Background()
+	store, err := Open(Config{Path: filepath.Join(t.TempDir(), "history.db"), Retention: 48 * time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = store.Close() }()
+
+	now := time.Unix(1700000000, 0)
+	store.now = func() time.Time { return now }
+
+	scanAge := func(value float64) []metrics.Metric {
+		return []metrics.Metric{metrics.NewMetric("minutesSinceLastScan", metrics.Gauge, value, map[string]string{"scanName": "daily"})}
+	}
+	for _, s := range []*metrics.Snapshot{
+		metrics.NewSnapshot(now.Add(-72*time.Hour), scanAge(1), nil),
+		metrics.NewSnapshot(now.Add(-24*time.Hour), scanAge(2), nil),
+		metrics.NewSnapshot(now, scanAge(3), nil),
+	} {
+		if err := store.RecordSnapshot(ctx, s); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	points, err := store.Values(ctx, "minutesSinceLastScan", time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The first snapshot is past the retention.
+	if len(points) != 2 || points[0].Value != 2 || points[1].Value != 3 || points[1].Tags["scanName"] != "daily" {
+		t.Errorf("Values() = %+v", points)
+	}
+
+	running := sc.ScanResultFact{Org: "org", ID: "1", ScanName: "daily", Status: "Running", StartTime: now}
+	finished := running
+	finished.Status = "Completed"
+	finished.FinishTime = now.Add(time.Hour)
+	for _, f := range []sc.ScanResultFact{running, finished} {
+		if err := store.RecordScanResults([]sc.ScanResultFact{f}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var count int
+	var status string
+	if err := store.db.QueryRow(`SELECT COUNT(*), MAX(status) FROM scan_results`).Scan(&count, &status); err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 || status != "Completed" {
+		t.Errorf("scan_results has %d rows with status %q, want 1 Completed", count, status)
+	}
+}
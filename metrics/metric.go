@@ -0,0 +1,150 @@
+// Copyright 2022 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// NoneTagValue stands in for empty tag values, which not every sink accepts.
+	NoneTagValue = "none"
+
+	// OrgTagName is the tag holding the org a metric was collected from.
+	OrgTagName = "org"
+)
+
+// Kind tells sinks how a metric's value should be aggregated.
+type Kind int
+
+const (
+	// Gauge is a point-in-time value.
+	Gauge Kind = iota
+	// Counter is a count of events which happened during the cycle.
+	Counter
+	// Distribution is a sample whose spread across series is interesting, such as a duration.
+	Distribution
+)
+
+func (k Kind) String() string {
+	switch k {
+	case Gauge:
+		return "gauge"
+	case Counter:
+		return "counter"
+	case Distribution:
+		return "distribution"
+	default:
+		return fmt.Sprintf("Kind(%d)", int(k))
+	}
+}
+
+// Metric is a single value of a tagged series, as produced by a collector.
+type Metric struct {
+	// Name is the bare metric name; sinks add their own prefix.
+	Name  string
+	Tags  map[string]string
+	Value float64
+	Kind  Kind
+	// Unit is the singular unit of Value, such as "second"; empty for plain counts.
+	Unit string
+	// Timestamp is when the value was observed at the source, if that's known and differs from the collection time.
+	Timestamp time.Time
+	// Collector is the name of the collector which produced the metric; empty for self-metrics.
+	Collector string
+	// Org is the org the collector ran against, empty for admin collectors. Unlike the org tag, which admin
+	// collectors may set too, it identifies the source which produced the metric.
+	Org string
+}
+
+// NewMetric builds a metric, replacing empty tag values with NoneTagValue.
+func NewMetric(name string, kind Kind, value float64, tags map[string]string) Metric {
+	m := Metric{
+		Name:  name,
+		Kind:  kind,
+		Value: value,
+	}
+	if len(tags) > 0 {
+		m.Tags = make(map[string]string, len(tags))
+		for k, v := range tags {
+			if v == "" {
+				v = NoneTagValue
+			}
+			m.Tags[k] = v
+		}
+	}
+	return m
+}
+
+// WithUnit returns a copy of the metric with the unit set.
+func (m Metric) WithUnit(unit string) Metric {
+	m.Unit = unit
+	return m
+}
+
+// WithTimestamp returns a copy of the metric with the source timestamp set.
+func (m Metric) WithTimestamp(t time.Time) Metric {
+	m.Timestamp = t
+	return m
+}
+
+// TagStrings returns the tags as sorted key:value strings.
+func (m Metric) TagStrings() []string {
+	var tagStrings []string
+	for k, v := range m.Tags {
+		tagStrings = append(tagStrings, fmt.Sprintf("%s:%s", k, v))
+	}
+	sort.Strings(tagStrings)
+	return tagStrings
+}
+
+// Key identifies the series the metric belongs to, as name[k:v,...] with sorted tags.
+// This is also the encoding the go-baseapp datadog emitter parses tags from.
+func (m Metric) Key() string {
+	tagStrings := m.TagStrings()
+	if len(tagStrings) == 0 {
+		return m.Name
+	}
+
+	return m.Name + fmt.Sprintf("[%s]", strings.Join(tagStrings, ","))
+}
+
+// Merge combines metrics from several sources into one list sorted by key.
+// Counters for the same series are summed; for every other kind the last value wins.
+func Merge(metrics ...[]Metric) []Metric {
+	byKey := make(map[string]Metric)
+	for _, list := range metrics {
+		for _, m := range list {
+			key := m.Key()
+			if existing, ok := byKey[key]; ok && m.Kind == Counter && existing.Kind == Counter {
+				m.Value += existing.Value
+			}
+			byKey[key] = m
+		}
+	}
+
+	merged := make([]Metric, 0, len(byKey))
+	for _, m := range byKey {
+		merged = append(merged, m)
+	}
+	sort.Slice(merged, func(i, j int) bool {
+		return merged[i].Key() < merged[j].Key()
+	})
+
+	return merged
+}
@@ -12,13 +12,13 @@
 // See the License for the specific language governing permissions and
 // limitations under the License.
 
-package sc
+package metrics
 
 import (
 	"testing"
 )
 
-func Test_buildTaggedMetricString(t *testing.T) {
+func TestMetric_Key(t *testing.T) {
 
 	tests := []struct {
 		name       string
@@ -32,24 +32,24 @@ func Test_buildTaggedMetricString(t *testing.T) {
 		},
 		{
 			name:       "metric with no tags",
-			metricName: scanDurationSecondsMetricName,
-			want:       scanDurationSecondsMetricName,
+			metricName: "scanDurationSeconds",
+			want:       "scanDurationSeconds",
 		},
 		{
 			name:       "metric with single tag",
-			metricName: scanDurationSecondsMetricName,
+			metricName: "scanDurationSeconds",
 			tagMap:     map[string]string{"foo-key": "foo-value"},
 			want:       `scanDurationSeconds[foo-key:foo-value]`,
 		},
 		{
 			name:       "metric with single tag(empty value)",
-			metricName: scanDurationSecondsMetricName,
+			metricName: "scanDurationSeconds",
 			tagMap:     map[string]string{"foo-key": ""},
 			want:       `scanDurationSeconds[foo-key:none]`,
 		},
 		{
 			name:       "metric with multi tag",
-			metricName: scanDurationSecondsMetricName,
+			metricName: "scanDurationSeconds",
 			tagMap: map[string]string{
 				"foo-key": "foo-value",
 				"bar-key": "bar-value",
@@ -59,9 +59,42 @@ func Test_buildTaggedMetricString(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := buildTaggedMetricString(tt.metricName, tt.tagMap); got != tt.want {
-				t.Errorf("buildTaggedMetricString() = %v, want %v", got, tt.want)
+			if got := NewMetric(tt.metricName, Gauge, 0, tt.tagMap).Key(); got != tt.want {
+				t.Errorf("Key() = %v, want %v", got, tt.want)
 			}
 		})
 	}
 }
+
+func TestMerge(t *testing.T) {
+	tags := map[string]string{"org": "foo"}
+
+	merged := Merge(
+		[]Metric{
+			NewMetric("failures", Counter, 1, tags),
+			NewMetric("ipCount", Gauge, 10, tags),
+		},
+		[]Metric{
+			NewMetric("ipCount", Gauge, 20, tags),
+			NewMetric("failures", Counter, 2, tags),
+			NewMetric("failures", Counter, 1, nil),
+		},
+	)
+
+	want := []struct {
+		key   string
+		value float64
+	}{
+		{key: "failures", value: 1},
+		{key: "failures[org:foo]", value: 3},
+		{key: "ipCount[org:foo]", value: 20},
+	}
+	if len(merged) != len(want) {
+		t.Fatalf("Merge() returned %d metrics, want %d", len(merged), len(want))
+	}
+	for i, w := range want {
+		if merged[i].Key() != w.key || merged[i].Value != w.value {
+			t.Errorf("Merge()[%d] = %s %v, want %s %v", i, merged[i].Key(), merged[i].Value, w.key, w.value)
+		}
+	}
+}
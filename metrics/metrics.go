@@ -16,48 +16,74 @@ package metrics
 
 import (
 	"fmt"
+	"sort"
+	"sync"
 
 	"github.com/rcrowley/go-metrics"
 )
 
-var (
-	registry metrics.Registry = metrics.DefaultRegistry
-)
-
 const (
-	metricPrefix = "tenablesc"
+	// Prefix namespaces every metric name when it leaves the process.
+	Prefix = "tenablesc"
 )
 
-// Okay, so all of this is currently _massive_ overkill because it's designed for a
-// multithreaded solution, which we do not have here.
-// The primary purpose of sc-metrics is to emit point-in-time metrics which _may age out_; this registry
-// appears to continue to emit metrics at their last value? forever? which is bad and wrong.
-
-// ResetRegistry creates a new registry, discarding all old metrics
-func ResetRegistry() {
-	registry = metrics.NewRegistry()
+// NewRegistry builds a go-metrics registry holding only the given metrics, with tags encoded into the names.
+// It exists for the go-baseapp datadog emitter; a fresh registry per cycle means series which are no longer
+// collected stop being emitted instead of repeating their last value forever.
+func NewRegistry(ms []Metric) metrics.Registry {
+	registry := metrics.NewRegistry()
+	for _, m := range ms {
+		metrics.GetOrRegisterGaugeFloat64(fmt.Sprintf("%s.%s", Prefix, m.Key()), registry).Update(m.Value)
+	}
+	return registry
 }
 
-// SetRegistry updates the default registry to the provided one
-func SetRegistry(r metrics.Registry) {
-	registry = r
+// SelfMetrics holds metrics about the exporter itself, such as how many cycles in a row have failed, which
+// outlive any single cycle's snapshot.
+type SelfMetrics struct {
+	mu     sync.Mutex
+	values map[string]Metric
 }
 
-// GetRegistry returns the current metrics  registry
-func GetRegistry() metrics.Registry {
-	return registry
+// NewSelfMetrics returns an empty store.
+func NewSelfMetrics() *SelfMetrics {
+	return &SelfMetrics{
+		values: make(map[string]Metric),
+	}
 }
 
-// Update sets the provided metric (name) to the specified value
-func Update(name string, value int64) {
-	metrics.GetOrRegisterGauge(fmt.Sprintf("%s.%s", metricPrefix, name), GetRegistry()).Update(value)
+// Set replaces the series' value.
+func (s *SelfMetrics) Set(m Metric) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.values[m.Key()] = m
 }
 
-// Increment adds the specified value to the provided metric's (name) current value
-func Increment(name string, value int64) {
-	gauge := metrics.GetOrRegisterGauge(fmt.Sprintf("%s.%s", metricPrefix, name), GetRegistry())
+// Increment adds value to the named untagged gauge, starting from zero.
+func (s *SelfMetrics) Increment(name string, value float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	m, ok := s.values[name]
+	if !ok {
+		m = NewMetric(name, Gauge, 0, nil)
+	}
+	m.Value += value
+	s.values[name] = m
+}
 
-	oldValue := gauge.Value()
+// Metrics returns the current value of every series, sorted by key.
+func (s *SelfMetrics) Metrics() []Metric {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
-	gauge.Update(oldValue + value)
+	ms := make([]Metric, 0, len(s.values))
+	for _, m := range s.values {
+		ms = append(ms, m)
+	}
+	sort.Slice(ms, func(i, j int) bool {
+		return ms[i].Key() < ms[j].Key()
+	})
+	return cloneMetrics(ms)
 }
@@ -0,0 +1,117 @@
+// Copyright 2022 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// Output formats for WriteRecords.
+const (
+	JSONOutput   = "json"
+	NDJSONOutput = "ndjson"
+	CSVOutput    = "csv"
+	TableOutput  = "table"
+)
+
+// OutputFormats lists every format WriteRecords accepts.
+var OutputFormats = []string{JSONOutput, NDJSONOutput, CSVOutput, TableOutput}
+
+// Record is the stable, flattened form of a metric written by WriteRecords.
+type Record struct {
+	Name      string            `json:"name"`
+	Tags      map[string]string `json:"tags"`
+	Value     float64           `json:"value"`
+	Unit      string            `json:"unit"`
+	Collector string            `json:"collector"`
+	Org       string            `json:"org"`
+}
+
+// RecordOf flattens a metric; org is empty for metrics without an org tag.
+func RecordOf(m Metric) Record {
+	tags := make(map[string]string, len(m.Tags))
+	for k, v := range m.Tags {
+		tags[k] = v
+	}
+	return Record{
+		Name:      m.Name,
+		Tags:      tags,
+		Value:     m.Value,
+		Unit:      m.Unit,
+		Collector: m.Collector,
+		Org:       m.Tags[OrgTagName],
+	}
+}
+
+// ValidOutputFormat reports whether WriteRecords accepts the format.
+func ValidOutputFormat(format string) bool {
+	for _, f := range OutputFormats {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
+
+// WriteRecords writes the metrics in the given format. The csv and table formats write tags as sorted,
+// comma separated key:value pairs.
+func WriteRecords(w io.Writer, format string, ms []Metric) error {
+	records := make([]Record, 0, len(ms))
+	for _, m := range ms {
+		records = append(records, RecordOf(m))
+	}
+
+	switch format {
+	case JSONOutput:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(records)
+	case NDJSONOutput:
+		encoder := json.NewEncoder(w)
+		for _, r := range records {
+			if err := encoder.Encode(r); err != nil {
+				return err
+			}
+		}
+		return nil
+	case CSVOutput:
+		out := csv.NewWriter(w)
+		_ = out.Write([]string{"name", "tags", "value", "unit", "collector", "org"})
+		for i, r := range records {
+			_ = out.Write([]string{r.Name, strings.Join(ms[i].TagStrings(), ","), formatValue(r.Value), r.Unit, r.Collector, r.Org})
+		}
+		out.Flush()
+		return out.Error()
+	case TableOutput:
+		out := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(out, "NAME\tTAGS\tVALUE\tUNIT\tCOLLECTOR\tORG")
+		for i, r := range records {
+			fmt.Fprintf(out, "%s\t%s\t%s\t%s\t%s\t%s\n", r.Name, strings.Join(ms[i].TagStrings(), ","), formatValue(r.Value), r.Unit, r.Collector, r.Org)
+		}
+		return out.Flush()
+	default:
+		return fmt.Errorf("unknown output format %q", format)
+	}
+}
+
+func formatValue(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
@@ -0,0 +1,54 @@
+// Copyright 2022 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestWriteRecords(t *testing.T) {
+	m := NewMetric("scanDurationSeconds", Distribution, 3600.5, map[string]string{OrgTagName: "foo", "scanName": "daily"}).WithUnit("second")
+	m.Collector = "scanDurations"
+	ms := []Metric{m, NewMetric("failedUpdate", Gauge, 0, nil)}
+
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{
+			format: CSVOutput,
+			want: "name,tags,value,unit,collector,org\n" +
+				"scanDurationSeconds,\"org:foo,scanName:daily\",3600.5,second,scanDurations,foo\n" +
+				"failedUpdate,,0,,,\n",
+		},
+		{
+			format: NDJSONOutput,
+			want: `{"name":"scanDurationSeconds","tags":{"org":"foo","scanName":"daily"},"value":3600.5,"unit":"second","collector":"scanDurations","org":"foo"}` + "\n" +
+				`{"name":"failedUpdate","tags":{},"value":0,"unit":"","collector":"","org":""}` + "\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			var out bytes.Buffer
+			if err := WriteRecords(&out, tt.format, ms); err != nil {
+				t.Fatalf("WriteRecords() error = %v", err)
+			}
+			if got := out.String(); got != tt.want {
+				t.Errorf("WriteRecords() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
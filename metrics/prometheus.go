@@ -0,0 +1,114 @@
+// Copyright 2022 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// PrometheusContentType is the classic Prometheus text exposition format.
+	PrometheusContentType = "text/plain; version=0.0.4; charset=utf-8"
+	// OpenMetricsContentType is the OpenMetrics text format, which differs from the classic one only by the trailing
+	// EOF marker for what we expose.
+	OpenMetricsContentType = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+)
+
+// WritePrometheus writes the metrics in the Prometheus text format, as tenablesc_<name> with tags as labels.
+//
+// Every series is exposed as a gauge: our counters count events within a single cycle rather than since the
+// process started, so they would break rate(), and distributions are exposed per series for the same reason.
+// Source timestamps aren't written either, as Prometheus rejects samples older than its head block.
+func WritePrometheus(w io.Writer, ms []Metric, openMetrics bool) error {
+	byName := make(map[string][]Metric)
+	for _, m := range ms {
+		name := PrometheusMetricName(m)
+		byName[name] = append(byName[name], m)
+	}
+
+	names := make([]string, 0, len(byName))
+	for name := range byName {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	out := bufio.NewWriter(w)
+	for _, name := range names {
+		fmt.Fprintf(out, "# TYPE %s gauge\n", name)
+		for _, m := range byName[name] {
+			out.WriteString(name)
+			writePrometheusLabels(out, m.Tags)
+			out.WriteByte(' ')
+			out.WriteString(strconv.FormatFloat(m.Value, 'g', -1, 64))
+			out.WriteByte('\n')
+		}
+	}
+	if openMetrics {
+		out.WriteString("# EOF\n")
+	}
+
+	return out.Flush()
+}
+
+func writePrometheusLabels(out *bufio.Writer, tags map[string]string) {
+	if len(tags) == 0 {
+		return
+	}
+
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	out.WriteByte('{')
+	for i, k := range keys {
+		if i > 0 {
+			out.WriteByte(',')
+		}
+		out.WriteString(PrometheusName(k))
+		out.WriteString(`="`)
+		out.WriteString(labelValueEscaper.Replace(tags[k]))
+		out.WriteByte('"')
+	}
+	out.WriteByte('}')
+}
+
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// PrometheusMetricName is the name a metric is exposed as to Prometheus, tenablesc_<name>.
+func PrometheusMetricName(m Metric) string {
+	return PrometheusName(Prefix + "_" + m.Name)
+}
+
+// PrometheusName replaces any character not allowed in metric and label names with an underscore.
+func PrometheusName(s string) string {
+	var b strings.Builder
+	for i, r := range s {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r == '_':
+		case r >= '0' && r <= '9' && i > 0:
+		default:
+			r = '_'
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,47 @@
+// Copyright 2022 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestWritePrometheus(t *testing.T) {
+	ms := []Metric{
+		NewMetric("scanDurationSeconds", Distribution, 3600, map[string]string{OrgTagName: "foo", "scanName": `weekly "full"`}),
+		NewMetric("collectorFailures", Counter, 2, map[string]string{"collector": "scanAge", "error-class": "http"}),
+		NewMetric("failedUpdate", Gauge, 0, nil),
+		NewMetric("scanDurationSeconds", Distribution, 60, map[string]string{OrgTagName: "bar", "scanName": "daily"}),
+	}
+
+	want := `# TYPE tenablesc_collectorFailures gauge
+tenablesc_collectorFailures{collector="scanAge",error_class="http"} 2
+# TYPE tenablesc_failedUpdate gauge
+tenablesc_failedUpdate 0
+# TYPE tenablesc_scanDurationSeconds gauge
+tenablesc_scanDurationSeconds{org="foo",scanName="weekly \"full\""} 3600
+tenablesc_scanDurationSeconds{org="bar",scanName="daily"} 60
+# EOF
+`
+
+	var out bytes.Buffer
+	if err := WritePrometheus(&out, ms, true); err != nil {
+		t.Fatalf("WritePrometheus() error = %v", err)
+	}
+	if got := out.String(); got != want {
+		t.Errorf("WritePrometheus() =\n%s\nwant\n%s", got, want)
+	}
+}
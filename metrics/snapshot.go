@@ -0,0 +1,80 @@
+// Copyright 2022 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"time"
+)
+
+// Snapshot is everything gathered in a single collection cycle. It is immutable once built, so it can be handed
+// to any number of sinks, concurrently if need be, without copying.
+type Snapshot struct {
+	time    time.Time
+	metrics []Metric
+	failed  []Source
+}
+
+// NewSnapshot merges the metrics of a cycle collected at the given time, along with the sources which failed.
+func NewSnapshot(at time.Time, metrics []Metric, failed []Source) *Snapshot {
+	return &Snapshot{
+		time:    at,
+		metrics: cloneMetrics(Merge(metrics)),
+		failed:  append([]Source(nil), failed...),
+	}
+}
+
+// Time returns when the cycle was collected.
+func (s *Snapshot) Time() time.Time {
+	return s.time
+}
+
+// Metrics returns a copy of the cycle's metrics, sorted by key.
+func (s *Snapshot) Metrics() []Metric {
+	return cloneMetrics(s.metrics)
+}
+
+// Failed returns the sources which failed during the cycle.
+func (s *Snapshot) Failed() []Source {
+	return append([]Source(nil), s.failed...)
+}
+
+// Len returns the number of series in the snapshot.
+func (s *Snapshot) Len() int {
+	return len(s.metrics)
+}
+
+// With returns a new snapshot with extra metrics merged in, leaving s untouched.
+func (s *Snapshot) With(extra ...[]Metric) *Snapshot {
+	combined := append([]Metric(nil), s.metrics...)
+	for _, list := range extra {
+		combined = append(combined, list...)
+	}
+	return NewSnapshot(s.time, combined, s.failed)
+}
+
+func cloneMetrics(metrics []Metric) []Metric {
+	clones := make([]Metric, len(metrics))
+	for i, m := range metrics {
+		if m.Tags != nil {
+			tags := make(map[string]string, len(m.Tags))
+			for k, v := range m.Tags {
+				tags[k] = v
+			}
+			m.Tags = tags
+		}
+		clones[i] = m
+	}
+	return clones
+}
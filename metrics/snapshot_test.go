@@ -0,0 +1,50 @@
+// Copyright 2022 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	gometrics "github.com/rcrowley/go-metrics"
+)
+
+func TestSnapshot_With(t *testing.T) {
+	snapshot := NewSnapshot(time.Now(), []Metric{
+		NewMetric("collectorFailures", Counter, 1, map[string]string{"collector": "scanAge"}),
+		NewMetric("scanCount", Gauge, 5, map[string]string{OrgTagName: "foo"}),
+	}, []Source{{Collector: "scanAge", Org: "foo"}})
+
+	// Callers only ever get copies.
+	snapshot.Metrics()[1].Tags[OrgTagName] = "bar"
+
+	extended := snapshot.With([]Metric{NewMetric("collectorFailures", Counter, 1, map[string]string{"collector": "scanAge"})})
+
+	if got := snapshot.Metrics(); len(got) != 2 || got[0].Value != 1 || got[1].Tags[OrgTagName] != "foo" {
+		t.Errorf("original snapshot changed: %v", got)
+	}
+	if got := extended.Metrics(); len(got) != 2 || got[0].Value != 2 {
+		t.Errorf("With() = %v, want the counters summed", got)
+	}
+	if len(extended.Failed()) != 1 || !extended.Time().Equal(snapshot.Time()) {
+		t.Errorf("With() dropped the snapshot's failures or time")
+	}
+
+	registry := NewRegistry(extended.Metrics())
+	gauge, ok := registry.Get("tenablesc.scanCount[org:foo]").(gometrics.GaugeFloat64)
+	if !ok || gauge.Value() != 5 {
+		t.Errorf("registry is missing tenablesc.scanCount[org:foo]")
+	}
+}
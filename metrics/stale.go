@@ -0,0 +1,143 @@
+// Copyright 2022 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"fmt"
+	"time"
+)
+
+// StalePolicy decides what is emitted for a series which was emitted last cycle but not this one.
+type StalePolicy string
+
+const (
+	// DropStale stops emitting the series without any signal.
+	DropStale StalePolicy = "drop"
+	// ZeroStale emits the series one last time with a zero value.
+	ZeroStale StalePolicy = "zero"
+	// EventStale emits a seriesRemoved counter carrying the removed series' tags and name.
+	EventStale StalePolicy = "event"
+)
+
+const (
+	seriesRemovedMetricName = "seriesRemoved"
+	metricTagName           = "metric"
+)
+
+// ParseStalePolicy validates a policy from config; empty means DropStale.
+func ParseStalePolicy(s string) (StalePolicy, error) {
+	switch p := StalePolicy(s); p {
+	case "":
+		return DropStale, nil
+	case DropStale, ZeroStale, EventStale:
+		return p, nil
+	default:
+		return "", fmt.Errorf("unknown stale series policy %q", s)
+	}
+}
+
+// Source is a collector run against a single org; OrgTagName's value is NoneTagValue for admin collectors.
+type Source struct {
+	Collector string `json:"collector"`
+	Org       string `json:"org"`
+}
+
+// SourceOf returns the source which produced the metric.
+func SourceOf(m Metric) Source {
+	org := m.Org
+	if org == "" {
+		org = NoneTagValue
+	}
+	return Source{Collector: m.Collector, Org: org}
+}
+
+// FailedSources is a set of sources which failed in a cycle.
+type FailedSources map[Source]bool
+
+// NewFailedSources builds the set from a snapshot's failed sources.
+func NewFailedSources(failed []Source) FailedSources {
+	sources := make(FailedSources, len(failed))
+	for _, source := range failed {
+		sources[source] = true
+	}
+	return sources
+}
+
+// Covers reports whether m's source failed.
+func (f FailedSources) Covers(m Metric) bool {
+	return f[SourceOf(m)]
+}
+
+// SeriesTracker remembers the series emitted each cycle so it can tell when one disappears.
+//
+// Only series produced by collectors are tracked; self-metrics such as failure counts come and go by design.
+// A series whose source failed this cycle is held rather than treated as removed, so the policy is only applied
+// to series which really went away, such as deleted scans or assets, and not to collection failures.
+type SeriesTracker struct {
+	policy   StalePolicy
+	previous map[string]Metric
+}
+
+// NewSeriesTracker returns a tracker applying the given policy.
+func NewSeriesTracker(policy StalePolicy) *SeriesTracker {
+	return &SeriesTracker{
+		policy:   policy,
+		previous: make(map[string]Metric),
+	}
+}
+
+// Track records this cycle's metrics, and returns any extra metrics the policy calls for to mark removed series.
+func (t *SeriesTracker) Track(current []Metric, failed []Source) []Metric {
+	failedSources := NewFailedSources(failed)
+
+	seen := make(map[string]Metric, len(current))
+	for _, m := range current {
+		if m.Collector == "" {
+			continue
+		}
+		seen[m.Key()] = m
+	}
+
+	var removed []Metric
+	for key, m := range t.previous {
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		if failedSources.Covers(m) {
+			seen[key] = m
+			continue
+		}
+
+		switch t.policy {
+		case ZeroStale:
+			zeroed := m
+			zeroed.Value = 0
+			zeroed.Timestamp = time.Time{}
+			removed = append(removed, zeroed)
+		case EventStale:
+			tags := map[string]string{metricTagName: m.Name}
+			for k, v := range m.Tags {
+				tags[k] = v
+			}
+			event := NewMetric(seriesRemovedMetricName, Counter, 1, tags)
+			event.Collector = m.Collector
+			event.Org = m.Org
+			removed = append(removed, event)
+		}
+	}
+
+	t.previous = seen
+	return Merge(removed)
+}
@@ -0,0 +1,87 @@
+// Copyright 2022 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"testing"
+)
+
+func collectorMetric(name, org, scan string, value float64) Metric {
+	m := NewMetric(name, Gauge, value, map[string]string{OrgTagName: org, "scanName": scan})
+	m.Collector, m.Org = "scanAge", org
+	return m
+}
+
+func TestSeriesTracker_Track(t *testing.T) {
+	first := []Metric{
+		collectorMetric("minutesSinceLastScan", "foo", "daily", 10),
+		collectorMetric("minutesSinceLastScan", "foo", "weekly", 20),
+		collectorMetric("minutesSinceLastScan", "bar", "daily", 30),
+		NewMetric("apiCacheHits", Counter, 3, nil),
+		// Admin collectors may tag series with an org, but their source has none.
+		{Name: "repositoryRunning", Tags: map[string]string{OrgTagName: "foo"}, Collector: "repositories"},
+	}
+	// foo's weekly scan was deleted, bar's collector and the admin repositories collector failed, and the
+	// self-metric is simply absent.
+	second := []Metric{
+		collectorMetric("minutesSinceLastScan", "foo", "daily", 11),
+	}
+	failed := []Source{{Collector: "scanAge", Org: "bar"}, {Collector: "repositories", Org: NoneTagValue}}
+
+	tests := []struct {
+		policy StalePolicy
+		want   []string
+		value  float64
+	}{
+		{
+			policy: DropStale,
+		},
+		{
+			policy: ZeroStale,
+			want:   []string{"minutesSinceLastScan[org:foo,scanName:weekly]"},
+			value:  0,
+		},
+		{
+			policy: EventStale,
+			want:   []string{"seriesRemoved[metric:minutesSinceLastScan,org:foo,scanName:weekly]"},
+			value:  1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.policy), func(t *testing.T) {
+			tracker := NewSeriesTracker(tt.policy)
+
+			if removed := tracker.Track(first, nil); len(removed) != 0 {
+				t.Fatalf("first cycle removed %v", removed)
+			}
+
+			removed := tracker.Track(second, failed)
+			if len(removed) != len(tt.want) {
+				t.Fatalf("Track() = %v, want %v", removed, tt.want)
+			}
+			for i, key := range tt.want {
+				if removed[i].Key() != key || removed[i].Value != tt.value {
+					t.Errorf("Track()[%d] = %s %v, want %s %v", i, removed[i].Key(), removed[i].Value, key, tt.value)
+				}
+			}
+
+			// Both recover without their series, which are now really gone; foo's weekly scan is only reported once.
+			removed = tracker.Track(second, nil)
+			if tt.policy != DropStale && (len(removed) != 2 || removed[0].Tags[OrgTagName] != "bar" || removed[1].Collector != "repositories") {
+				t.Errorf("Track() after recovery = %v, want bar's daily scan and foo's repository", removed)
+			}
+		})
+	}
+}
@@ -15,9 +15,45 @@
 package sc
 
 import (
+	"errors"
+	"fmt"
 	"strconv"
+
+	"github.com/palantir/tenablesc-metrics/metrics"
+	"github.com/rs/zerolog/log"
 )
 
+func init() {
+	RegisterCollector(assetIPCountCollectorName, true, newAssetIPCountCollector)
+}
+
+type assetIPCountCollector struct{}
+
+func newAssetIPCountCollector(cfg CollectorConfig) (Collector, error) {
+	return assetIPCountCollector{}, cfg.Decode(&struct{}{})
+}
+
+func (assetIPCountCollector) Name() string {
+	return assetIPCountCollectorName
+}
+
+func (assetIPCountCollector) Scope() Scope {
+	return OrgScope
+}
+
+func (assetIPCountCollector) Collect(t *Target) ([]metrics.Metric, error) {
+	var collected []metrics.Metric
+
+	assetIPCounts, err := t.Client.getAssetIPCounts()
+	for assetName, metric := range assetIPCounts {
+		collected = append(collected, metrics.NewMetric(ipCountMetricName, metrics.Gauge, float64(metric), map[string]string{orgTagName: t.Org, assetNameTagName: assetName}))
+	}
+
+	return collected, err
+}
+
+// getAssetIPCounts returns a set of asset names and their IP counts.
+// Assets whose IP count can't be parsed are left out, and reported in the returned error.
 func (c *Client) getAssetIPCounts() (map[string]int64, error) {
 
 	assetMap := make(map[string]int64)
@@ -27,10 +63,13 @@ func (c *Client) getAssetIPCounts() (map[string]int64, error) {
 		return assetMap, err
 	}
 
+	var errs []error
 	for _, asset := range assets {
 		ipCount, err := strconv.ParseInt(string(asset.IPCount), 10, 64)
 		if err != nil {
-			return assetMap, err
+			log.Err(err).Str("asset name", asset.Name).Msg("Failed to parse asset IP count, skipping.")
+			errs = append(errs, fmt.Errorf("failed to parse IP count of asset %s: %w", asset.Name, err))
+			continue
 		}
 		if ipCount != -1 {
 			// ipCountMetricName -1 returns when it's mid-update; we should not update that metric with a -1
@@ -38,5 +77,5 @@ func (c *Client) getAssetIPCounts() (map[string]int64, error) {
 		}
 	}
 
-	return assetMap, nil
+	return assetMap, errors.Join(errs...)
 }
@@ -0,0 +1,176 @@
+// Copyright 2022 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sc
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/palantir/tenablesc-client/tenablesc"
+	"github.com/palantir/tenablesc-metrics/metrics"
+)
+
+// Clients are created fresh for every collection cycle, so caching responses on the client gives each
+// cycle a consistent snapshot of SC, and means collectors sharing an endpoint only pay for it once.
+
+const (
+	currentUserEndpoint     = "currentUser"
+	scansEndpoint           = "scans"
+	scanResultsEndpoint     = "scanResults"
+	assetsEndpoint          = "assets"
+	jobsEndpoint            = "jobs"
+	scanZonesEndpoint       = "scanZones"
+	scannersEndpoint        = "scanners"
+	feedsEndpoint           = "feeds"
+	repositoriesEndpoint    = "repositories"
+	organizationsEndpoint   = "organizations"
+	acceptRiskRulesEndpoint = "acceptRiskRules"
+	recastRiskRulesEndpoint = "recastRiskRules"
+)
+
+type responseCache struct {
+	mu      sync.Mutex
+	entries map[string]*cacheEntry
+}
+
+type cacheEntry struct {
+	once     sync.Once
+	value    interface{}
+	err      error
+	duration time.Duration
+
+	// hits and misses are guarded by the cache's mutex
+	hits, misses int64
+}
+
+func newResponseCache() *responseCache {
+	return &responseCache{entries: make(map[string]*cacheEntry)}
+}
+
+func (r *responseCache) entry(endpoint string) *cacheEntry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entry, exists := r.entries[endpoint]
+	if !exists {
+		entry = &cacheEntry{}
+		r.entries[endpoint] = entry
+		entry.misses++
+	} else {
+		entry.hits++
+	}
+	return entry
+}
+
+// cachedFetch calls fetch the first time endpoint is requested from the client, and replays its response,
+// including any error, for the rest of the cycle.
+// Concurrent first requests wait for the single fetch rather than racing to make their own.
+func cachedFetch[T any](c *Client, endpoint string, fetch func() (T, error)) (T, error) {
+	entry := c.cache.entry(endpoint)
+	entry.once.Do(func() {
+		start := time.Now()
+		entry.value, entry.err = fetch()
+		entry.duration = time.Since(start)
+	})
+
+	value, _ := entry.value.(T)
+	return value, entry.err
+}
+
+// cacheMetrics reports the client's cache hits, misses and fetch timings, tagged with the org.
+func (c *Client) cacheMetrics(org string) []metrics.Metric {
+	c.cache.mu.Lock()
+	defer c.cache.mu.Unlock()
+
+	var endpoints []string
+	for endpoint := range c.cache.entries {
+		endpoints = append(endpoints, endpoint)
+	}
+	sort.Strings(endpoints)
+
+	var cacheMetrics []metrics.Metric
+	for _, endpoint := range endpoints {
+		entry := c.cache.entries[endpoint]
+		tags := map[string]string{orgTagName: org, endpointTagName: endpoint}
+
+		cacheMetrics = append(cacheMetrics,
+			metrics.NewMetric(apiCacheHitsMetricName, metrics.Counter, float64(entry.hits), tags),
+			metrics.NewMetric(apiCacheMissesMetricName, metrics.Counter, float64(entry.misses), tags),
+			metrics.NewMetric(apiFetchMillisecondsMetricName, metrics.Gauge, float64(entry.duration.Milliseconds()), tags).WithUnit(millisecondUnit),
+		)
+	}
+	return cacheMetrics
+}
+
+// GetCurrentUser returns the user the client is logged in as, fetching it at most once.
+func (c *Client) GetCurrentUser() (*tenablesc.CurrentUser, error) {
+	return cachedFetch(c, currentUserEndpoint, c.Client.GetCurrentUser)
+}
+
+// GetAllScans returns every scan visible to the client, fetching them at most once.
+func (c *Client) GetAllScans() ([]*tenablesc.Scan, error) {
+	return cachedFetch(c, scansEndpoint, c.Client.GetAllScans)
+}
+
+// GetAllScanResults returns every scan result visible to the client, fetching them at most once.
+func (c *Client) GetAllScanResults() ([]*tenablesc.ScanResult, error) {
+	return cachedFetch(c, scanResultsEndpoint, c.Client.GetAllScanResults)
+}
+
+// GetAllAssets returns every asset visible to the client, fetching them at most once.
+func (c *Client) GetAllAssets() ([]*tenablesc.Asset, error) {
+	return cachedFetch(c, assetsEndpoint, c.Client.GetAllAssets)
+}
+
+// GetAllJobs returns the job queue, fetching it at most once.
+func (c *Client) GetAllJobs() ([]*tenablesc.Job, error) {
+	return cachedFetch(c, jobsEndpoint, c.Client.GetAllJobs)
+}
+
+// GetAllScanZones returns every scan zone, fetching them at most once.
+func (c *Client) GetAllScanZones() ([]*tenablesc.ScanZone, error) {
+	return cachedFetch(c, scanZonesEndpoint, c.Client.GetAllScanZones)
+}
+
+// GetAllScanners returns every scanner, fetching them at most once.
+func (c *Client) GetAllScanners() ([]*tenablesc.Scanner, error) {
+	return cachedFetch(c, scannersEndpoint, c.Client.GetAllScanners)
+}
+
+// GetAllFeeds returns the status of every plugin feed, fetching them at most once.
+func (c *Client) GetAllFeeds() (tenablesc.Feeds, error) {
+	return cachedFetch(c, feedsEndpoint, c.Client.GetAllFeeds)
+}
+
+// GetAllRepositories returns every repository it can decode, fetching them at most once; see fetchRepositories.
+func (c *Client) GetAllRepositories() ([]*tenablesc.Repository, error) {
+	return cachedFetch(c, repositoriesEndpoint, c.fetchRepositories)
+}
+
+// GetAllOrganizations returns every organization, fetching them at most once.
+func (c *Client) GetAllOrganizations() ([]*tenablesc.Organization, error) {
+	return cachedFetch(c, organizationsEndpoint, c.Client.GetAllOrganizations)
+}
+
+// GetAllAcceptRiskRules returns every accept risk rule, fetching them at most once.
+func (c *Client) GetAllAcceptRiskRules() ([]*tenablesc.AcceptRiskRule, error) {
+	return cachedFetch(c, acceptRiskRulesEndpoint, c.Client.GetAllAcceptRiskRules)
+}
+
+// GetAllRecastRiskRules returns every recast risk rule, fetching them at most once.
+func (c *Client) GetAllRecastRiskRules() ([]*tenablesc.RecastRiskRule, error) {
+	return cachedFetch(c, recastRiskRulesEndpoint, c.Client.GetAllRecastRiskRules)
+}
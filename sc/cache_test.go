@@ -0,0 +1,61 @@
+// Copyright 2022 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sc
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func Test_cachedFetch(t *testing.T) {
+	client := &Client{cache: newResponseCache()}
+
+	var fetches int64
+	fetch := func() ([]string, error) {
+		atomic.AddInt64(&fetches, 1)
+		return []string{"scan"}, nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			got, err := cachedFetch(client, scansEndpoint, fetch)
+			if err != nil || len(got) != 1 || got[0] != "scan" {
+				t.Errorf("cachedFetch() = %v, %v", got, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if fetches != 1 {
+		t.Errorf("fetched %d times, want 1", fetches)
+	}
+
+	for _, m := range client.cacheMetrics("org") {
+		switch m.Name {
+		case apiCacheHitsMetricName:
+			if m.Value != 9 {
+				t.Errorf("hits = %v, want 9", m.Value)
+			}
+		case apiCacheMissesMetricName:
+			if m.Value != 1 {
+				t.Errorf("misses = %v, want 1", m.Value)
+			}
+		}
+	}
+}
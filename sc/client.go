@@ -15,10 +15,135 @@
 package sc
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
 	"github.com/palantir/tenablesc-client/tenablesc"
 )
 
-// Client is the struct used to interact with SC
+const (
+	directRequestTimeout    = 5 * time.Minute
+	defaultAnalysisPageSize = 1000
+)
+
+// Client is the struct used to interact with SC.
+// The list endpoints used by collectors are cached for the lifetime of the client; see cache.go.
 type Client struct {
 	tenablesc.Client
+
+	cache *responseCache
+	// scanResults is the org's index of newest scan results; nil for clients without one, such as the admin client.
+	scanResults *orgScanResults
+	// org is the name SC reports for the client's org, and recorder receives every scan result it fetches.
+	org      string
+	recorder ScanResultRecorder
+
+	// url and apiKey are used for the requests tenablesc.Client can't make; see request.
+	url        string
+	apiKey     string
+	httpClient *http.Client
+}
+
+// request sends a request straight to the SC API, decoding the response field of SC's reply into dest.
+// It is for what the tenablesc client doesn't support, such as analysis tools it doesn't know, or lists where it
+// fails on a single record it can't decode.
+func (c *Client) request(method, endpoint string, query url.Values, body, dest interface{}) error {
+	var reqBody io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reqBody = bytes.NewReader(encoded)
+	}
+
+	u := strings.TrimSuffix(c.url, "/") + endpoint
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	req, err := http.NewRequest(method, u, reqBody)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("x-apikey", c.apiKey)
+	req.Header.Set("User-Agent", tenablesc.DefaultUserAgent)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to make request: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	var scResponse tenablesc.SCResponse
+	if err := json.NewDecoder(resp.Body).Decode(&scResponse); err != nil {
+		return fmt.Errorf("%s %s returned status %d and an unreadable body: %w", method, endpoint, resp.StatusCode, err)
+	}
+	if scResponse.ErrorCode != 0 || resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("%s %s returned status %d, error %d: %s", method, endpoint, resp.StatusCode, scResponse.ErrorCode, scResponse.ErrorMsg)
+	}
+	if err := json.Unmarshal(scResponse.Response, dest); err != nil {
+		return fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	return nil
+}
+
+// analyze runs an analysis query through request, for tools the SC client's Analyze doesn't know, and decodes
+// its results into dest.
+func (c *Client) analyze(analysis *tenablesc.Analysis, dest interface{}) (*tenablesc.AnalysisResponseContainer, error) {
+	var container tenablesc.AnalysisResponseContainer
+	if err := c.request(http.MethodPost, "/analysis", nil, analysis, &container); err != nil {
+		return nil, fmt.Errorf("failed %s analysis: %w", analysis.Query.Tool, err)
+	}
+	if len(container.Results) > 0 {
+		if err := json.Unmarshal(container.Results, dest); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal %s results: %w", analysis.Query.Tool, err)
+		}
+	}
+	return &container, nil
+}
+
+// analyzePages runs a query of the cumulative vulnerability database with the given tool, handling its results
+// pageSize at a time. T must be the result type the SC client expects for the tool.
+func analyzePages[T any](c *Client, tool string, filters []tenablesc.AnalysisFilter, pageSize int, handle func([]T) error) error {
+	for offset := 0; ; offset += pageSize {
+		analysis := &tenablesc.Analysis{
+			Type:       "vuln",
+			SourceType: "cumulative",
+			Query: tenablesc.AnalysisQuery{
+				Type:       "vuln",
+				SourceType: "cumulative",
+				Tool:       tool,
+				Filters:    filters,
+			},
+			Columns:     []tenablesc.BaseInfo{},
+			StartOffset: strconv.Itoa(offset),
+			EndOffset:   strconv.Itoa(offset + pageSize),
+		}
+
+		var page []T
+		resp, err := c.Analyze(analysis, &page)
+		if err != nil {
+			return err
+		}
+		if err := handle(page); err != nil {
+			return err
+		}
+
+		if len(page) < pageSize {
+			return nil
+		}
+		if total, err := strconv.Atoi(resp.TotalRecords); err == nil && offset+len(page) >= total {
+			return nil
+		}
+	}
 }
@@ -0,0 +1,167 @@
+// Copyright 2022 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sc
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/palantir/tenablesc-metrics/metrics"
+	"gopkg.in/yaml.v2"
+)
+
+// Scope describes which credentials a Collector needs to gather its metrics.
+type Scope int
+
+const (
+	// AdminScope collectors run once per cycle using the admin credentials.
+	AdminScope Scope = iota
+	// OrgScope collectors run once per configured organization using that organization's credentials.
+	OrgScope
+)
+
+func (s Scope) String() string {
+	switch s {
+	case AdminScope:
+		return "admin"
+	case OrgScope:
+		return "org"
+	default:
+		return fmt.Sprintf("Scope(%d)", int(s))
+	}
+}
+
+// Target is the SC session a Collector gathers metrics through.
+type Target struct {
+	Client *Client
+	// Org is the organization name the client is logged into; it is empty for admin scoped collectors.
+	Org string
+}
+
+// Collector gathers one family of metrics from SC.
+type Collector interface {
+	// Name is the key used to configure the collector in the collectors block.
+	Name() string
+	Scope() Scope
+	// Collect returns the metrics gathered from the target.
+	// A collector which fails partway should return whatever it did gather along with the error.
+	Collect(t *Target) ([]metrics.Metric, error)
+}
+
+// CollectorFactory builds a Collector from its configuration block.
+type CollectorFactory func(cfg CollectorConfig) (Collector, error)
+
+type collectorRegistration struct {
+	defaultEnabled bool
+	factory        CollectorFactory
+}
+
+var collectorRegistry = make(map[string]collectorRegistration)
+
+// RegisterCollector makes a collector available for configuration under the provided name.
+// It is intended to be called from init functions and panics if the name is already taken.
+func RegisterCollector(name string, defaultEnabled bool, factory CollectorFactory) {
+	if _, exists := collectorRegistry[name]; exists {
+		panic(fmt.Sprintf("collector %s registered twice", name))
+	}
+	collectorRegistry[name] = collectorRegistration{
+		defaultEnabled: defaultEnabled,
+		factory:        factory,
+	}
+}
+
+// RegisteredCollectorNames returns the sorted names of every known collector.
+func RegisteredCollectorNames() []string {
+	var names []string
+	for name := range collectorRegistry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// CollectorConfig is the configuration block for a single collector.
+// Apart from enabled, every key is collector specific and is decoded by the collector's factory.
+type CollectorConfig struct {
+	Enabled *bool
+
+	options map[string]interface{}
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler
+func (c *CollectorConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var raw map[string]interface{}
+	if err := unmarshal(&raw); err != nil {
+		return err
+	}
+
+	if enabled, ok := raw["enabled"]; ok {
+		b, ok := enabled.(bool)
+		if !ok {
+			return fmt.Errorf("enabled must be a boolean, got %v", enabled)
+		}
+		c.Enabled = &b
+		delete(raw, "enabled")
+	}
+	c.options = raw
+
+	return nil
+}
+
+// Decode strictly unmarshals the collector specific options into dest.
+// Fields of dest which are not present in the config are left untouched, so defaults can be set beforehand.
+func (c CollectorConfig) Decode(dest interface{}) error {
+	if len(c.options) == 0 {
+		return nil
+	}
+
+	bytes, err := yaml.Marshal(c.options)
+	if err != nil {
+		return err
+	}
+
+	return yaml.UnmarshalStrict(bytes, dest)
+}
+
+// EnabledCollectors builds every enabled collector, sorted by name.
+func (c Config) EnabledCollectors() ([]Collector, error) {
+	for name := range c.Collectors {
+		if _, exists := collectorRegistry[name]; !exists {
+			return nil, fmt.Errorf("unknown collector %s", name)
+		}
+	}
+
+	var collectors []Collector
+	for _, name := range RegisteredCollectorNames() {
+		registration := collectorRegistry[name]
+		cfg := c.Collectors[name]
+
+		enabled := registration.defaultEnabled
+		if cfg.Enabled != nil {
+			enabled = *cfg.Enabled
+		}
+		if !enabled {
+			continue
+		}
+
+		collector, err := registration.factory(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to configure collector %s: %w", name, err)
+		}
+		collectors = append(collectors, collector)
+	}
+
+	return collectors, nil
+}
@@ -0,0 +1,107 @@
+// Copyright 2022 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sc
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+func TestConfig_EnabledCollectors(t *testing.T) {
+
+	tests := []struct {
+		name    string
+		config  string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name: "defaults",
+			want: []string{assetIPCountCollectorName, feedsCollectorName, jobQueueCollectorName, repositoriesCollectorName, riskRulesCollectorName, scanAgeCollectorName, scanDurationsCollectorName, scannerStatusCollectorName},
+		},
+		{
+			name: "disabled collector",
+			config: `
+collectors:
+  assetIPCount:
+    enabled: false
+  scanAge:
+    enabled: true
+`,
+			want: []string{feedsCollectorName, jobQueueCollectorName, repositoriesCollectorName, riskRulesCollectorName, scanAgeCollectorName, scanDurationsCollectorName, scannerStatusCollectorName},
+		},
+		{
+			name: "unknown collector",
+			config: `
+collectors:
+  notACollector: {}
+`,
+			wantErr: true,
+		},
+		{
+			name: "unknown option",
+			config: `
+collectors:
+  scannerStatus:
+    notAnOption: 1
+`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var c Config
+			if err := yaml.UnmarshalStrict([]byte(tt.config), &c); err != nil {
+				t.Fatalf("failed to parse config: %v", err)
+			}
+
+			collectors, err := c.EnabledCollectors()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("EnabledCollectors() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			var got []string
+			for _, collector := range collectors {
+				got = append(got, collector.Name())
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("EnabledCollectors() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCollectorConfig_Decode(t *testing.T) {
+	var c Config
+	config := `
+collectors:
+  jobQueue:
+    notStartedGracePeriod: 10m
+`
+	if err := yaml.UnmarshalStrict([]byte(config), &c); err != nil {
+		t.Fatalf("failed to parse config: %v", err)
+	}
+
+	collector, err := newJobQueueCollector(c.Collectors[jobQueueCollectorName])
+	if err != nil {
+		t.Fatalf("newJobQueueCollector() error = %v", err)
+	}
+	if got := collector.(*jobQueueCollector).NotStartedGracePeriod; got != 10*time.Minute {
+		t.Errorf("NotStartedGracePeriod = %v, want %v", got, 10*time.Minute)
+	}
+}
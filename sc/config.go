@@ -16,6 +16,7 @@ package sc
 
 import (
 	"fmt"
+	"net/http"
 	"sort"
 
 	"github.com/palantir/tenablesc-client/tenablesc"
@@ -27,6 +28,12 @@ type Config struct {
 	URL              string                 `yaml:"url"`
 	AdminCredentials Credentials            `yaml:"adminCredentials,omitempty"`
 	OrgCredentials   map[string]Credentials `yaml:"orgCredentials,omitempty"`
+	// Collectors enables, disables and configures collectors by name; unlisted collectors use their defaults.
+	Collectors map[string]CollectorConfig `yaml:"collectors,omitempty"`
+	// MaxConcurrency caps how many orgs and admin collectors are gathered from SC at once; defaults to 4.
+	MaxConcurrency int `yaml:"maxConcurrency,omitempty" validate:"gte=0"`
+	// StateFile persists the scan result index between runs. Without it the index only lives as long as the process.
+	StateFile string `yaml:"stateFile,omitempty"`
 }
 
 // Credentials containe the API credentials for SC
@@ -41,14 +48,21 @@ func (c Config) TenableAdminClient() (*Client, error) {
 }
 
 func (c Credentials) client(url string) (*Client, error) {
-	client := tenablesc.NewClient(url).SetAPIKey(c.AccessKey, c.SecretKey)
+	client := &Client{
+		Client:     *tenablesc.NewClient(url).SetAPIKey(c.AccessKey, c.SecretKey),
+		cache:      newResponseCache(),
+		url:        url,
+		apiKey:     fmt.Sprintf("accesskey=%s; secretkey=%s;", c.AccessKey, c.SecretKey),
+		httpClient: &http.Client{Timeout: directRequestTimeout},
+	}
 
+	// Validates the credentials, and primes the cache for anything that needs the org name.
 	_, err := client.GetCurrentUser()
 	if err != nil {
 		return nil, err
 	}
 
-	return &Client{Client: *client}, nil
+	return client, nil
 }
 
 // TenableOrgNames returns a slice of the org names for which credentials were provided
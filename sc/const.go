@@ -14,21 +14,79 @@
 
 package sc
 
+import (
+	"github.com/palantir/tenablesc-metrics/metrics"
+)
+
+const (
+	scannerStatusCollectorName  = "scannerStatus"
+	jobQueueCollectorName       = "jobQueue"
+	scanAgeCollectorName        = "scanAge"
+	scanDurationsCollectorName  = "scanDurations"
+	assetIPCountCollectorName   = "assetIPCount"
+	feedsCollectorName          = "feeds"
+	repositoriesCollectorName   = "repositories"
+	vulnSeverityCollectorName   = "vulnSeverity"
+	remediationSLACollectorName = "remediationSLA"
+	riskRulesCollectorName      = "riskRules"
+)
+
 const (
-	jobsNotStartedMetricName        = "jobsNotStarted"
-	jobQueueLengthMetricName        = "jobQueueLength"
-	unhealthyScannerCountMetricName = "unhealthyScannerCount"
-	healthyScannerCountMetricName   = "healthyScannerCount"
-	totalScannerCountMetricName     = "totalScannerCount"
-	ipCountMetricName               = "ipCount"
-	scanDurationSecondsMetricName   = "scanDurationSeconds"
-	minutesSinceLastScanMetricName  = "minutesSinceLastScan"
+	jobsNotStartedMetricName          = "jobsNotStarted"
+	jobQueueLengthMetricName          = "jobQueueLength"
+	unhealthyScannerCountMetricName   = "unhealthyScannerCount"
+	healthyScannerCountMetricName     = "healthyScannerCount"
+	totalScannerCountMetricName       = "totalScannerCount"
+	ipCountMetricName                 = "ipCount"
+	scanDurationSecondsMetricName     = "scanDurationSeconds"
+	minutesSinceLastScanMetricName    = "minutesSinceLastScan"
+	collectorFailuresMetricName       = "collectorFailures"
+	orgFailuresMetricName             = "orgFailures"
+	apiCacheHitsMetricName            = "apiCacheHits"
+	apiCacheMissesMetricName          = "apiCacheMisses"
+	apiFetchMillisecondsMetricName    = "apiFetchMilliseconds"
+	feedAgeMinutesMetricName          = "feedAgeMinutes"
+	feedStaleMetricName               = "feedStale"
+	feedUpdateRunningMetricName       = "feedUpdateRunning"
+	repositoryIPCountMetricName       = "repositoryIPCount"
+	repositoryRunningMetricName       = "repositoryRunning"
+	repositoryGenerateAgeMetricName   = "repositoryGenerateAgeMinutes"
+	repositoryVulnLifetimeMetricName  = "repositoryVulnLifetimeDays"
+	vulnerabilityCountMetricName      = "vulnerabilityCount"
+	assetVulnerabilityCountMetricName = "assetVulnerabilityCount"
+	slaBreachCountMetricName          = "slaBreachCount"
+	openFindingsByAgeMetricName       = "openFindingsByAge"
+	assetSLABreachCountMetricName     = "assetSLABreachCount"
+	assetOpenFindingsByAgeMetricName  = "assetOpenFindingsByAge"
+	riskRuleCountMetricName           = "riskRuleCount"
+	riskRulesWithoutExpiryMetricName  = "riskRulesWithoutExpiry"
+	riskRulesExpiringSoonMetricName   = "riskRulesExpiringSoon"
+	riskRulesExpiredMetricName        = "riskRulesExpired"
+	riskRuleFindingsMetricName        = "riskRuleFindings"
+	suppressedFindingsMetricName      = "suppressedFindings"
+
+	secondUnit      = "second"
+	minuteUnit      = "minute"
+	millisecondUnit = "millisecond"
+	dayUnit         = "day"
+)
 
+const (
 	scanZoneTagName = "scanZone"
 	jobTypeTagName  = "jobType"
 
-	orgTagName       = "org"
-	assetNameTagName = "assetName"
-	scanNameTagName  = "scanName"
-	noneTagValue     = "none"
+	orgTagName        = metrics.OrgTagName
+	assetNameTagName  = "assetName"
+	scanNameTagName   = "scanName"
+	collectorTagName  = "collector"
+	errorClassTagName = "errorClass"
+	endpointTagName   = "endpoint"
+	feedTagName       = "feed"
+	repositoryTagName = "repository"
+	vulnTypeTagName   = "vulnType"
+	severityTagName   = "severity"
+	ageBucketTagName  = "ageBucket"
+	ruleTypeTagName   = "ruleType"
+	ruleIDTagName     = "ruleID"
+	pluginIDTagName   = "pluginID"
 )
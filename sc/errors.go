@@ -0,0 +1,65 @@
+// Copyright 2022 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sc
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+
+	"github.com/palantir/tenablesc-client/tenablesc"
+)
+
+const (
+	authErrorClass    = "auth"
+	httpErrorClass    = "http"
+	scErrorClass      = "sc"
+	networkErrorClass = "network"
+	parseErrorClass   = "parse"
+	unknownErrorClass = "unknown"
+)
+
+// errorClass buckets an error into a small set of values suitable for use as a tag.
+func errorClass(err error) string {
+	var httpErr tenablesc.HTTPError
+	if errors.As(err, &httpErr) {
+		// SC answers 403 for both bad credentials and missing objects.
+		if httpErr.ResponseCode == http.StatusUnauthorized || httpErr.ResponseCode == http.StatusForbidden {
+			return authErrorClass
+		}
+		return httpErrorClass
+	}
+
+	var scErr tenablesc.SCError
+	if errors.As(err, &scErr) {
+		return scErrorClass
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return networkErrorClass
+	}
+
+	var numErr *strconv.NumError
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &numErr) || errors.As(err, &syntaxErr) || errors.As(err, &typeErr) {
+		return parseErrorClass
+	}
+
+	return unknownErrorClass
+}
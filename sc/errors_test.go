@@ -0,0 +1,74 @@
+// Copyright 2022 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sc
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"testing"
+
+	"github.com/palantir/tenablesc-client/tenablesc"
+)
+
+func Test_errorClass(t *testing.T) {
+
+	_, parseErr := strconv.ParseInt("not a number", 10, 64)
+
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{
+			name: "unknown",
+			err:  errors.New("something broke"),
+			want: unknownErrorClass,
+		},
+		{
+			name: "forbidden",
+			err:  fmt.Errorf("failed to get current user: %w", tenablesc.HTTPError{ResponseCode: 403}),
+			want: authErrorClass,
+		},
+		{
+			name: "server error",
+			err:  fmt.Errorf("failed to get scans: %w", tenablesc.HTTPError{ResponseCode: 500}),
+			want: httpErrorClass,
+		},
+		{
+			name: "sc error",
+			err:  fmt.Errorf("failed to get scans: %w", tenablesc.SCError{SCErrorCode: 143}),
+			want: scErrorClass,
+		},
+		{
+			name: "network",
+			err:  fmt.Errorf("failed to make request: %w", &url.Error{Op: "Get", URL: "https://sc.local", Err: errors.New("connection refused")}),
+			want: networkErrorClass,
+		},
+		{
+			name: "parse, joined",
+			err:  errors.Join(fmt.Errorf("failed to parse duration of scan foo: %w", parseErr)),
+			want: parseErrorClass,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := errorClass(tt.err); got != tt.want {
+				t.Errorf("errorClass() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+// Copyright 2022 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sc
+
+import (
+	"time"
+
+	"github.com/palantir/tenablesc-client/tenablesc"
+	"github.com/palantir/tenablesc-metrics/metrics"
+)
+
+func init() {
+	RegisterCollector(feedsCollectorName, true, newFeedsCollector)
+}
+
+// feedsCollector reports how fresh each plugin feed is; a stale feed means scans run with out of date plugins.
+type feedsCollector struct{}
+
+func newFeedsCollector(cfg CollectorConfig) (Collector, error) {
+	return feedsCollector{}, cfg.Decode(&struct{}{})
+}
+
+func (feedsCollector) Name() string {
+	return feedsCollectorName
+}
+
+func (feedsCollector) Scope() Scope {
+	return AdminScope
+}
+
+func (feedsCollector) Collect(t *Target) ([]metrics.Metric, error) {
+	feeds, err := t.Client.GetAllFeeds()
+	if err != nil {
+		return nil, err
+	}
+	return feedMetrics(feeds, time.Now()), nil
+}
+
+func feedMetrics(feeds tenablesc.Feeds, now time.Time) []metrics.Metric {
+	var collected []metrics.Metric
+	for name, feed := range feeds {
+		if feed == nil {
+			continue
+		}
+		tags := map[string]string{feedTagName: name}
+
+		// Feeds which have never been updated, such as those of unlicensed products, have no age to report.
+		if updated := epochStringToTime(string(feed.UpdateTime)); updated.Unix() > 0 {
+			collected = append(collected, metrics.NewMetric(feedAgeMinutesMetricName, metrics.Gauge, float64(int64(now.Sub(updated).Minutes())), tags).WithUnit(minuteUnit))
+		}
+		collected = append(collected,
+			metrics.NewMetric(feedStaleMetricName, metrics.Gauge, boolValue(feed.Stale.AsBool()), tags),
+			metrics.NewMetric(feedUpdateRunningMetricName, metrics.Gauge, boolValue(feed.UpdateRunning.AsBool()), tags),
+		)
+	}
+	return collected
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,63 @@
+// Copyright 2022 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sc
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/palantir/tenablesc-client/tenablesc"
+)
+
+func Test_feedMetrics(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	updated := func(ago time.Duration) tenablesc.ProbablyString {
+		return tenablesc.ProbablyString(strconv.FormatInt(now.Add(-ago).Unix(), 10))
+	}
+
+	feeds := tenablesc.Feeds{
+		"active":  {UpdateTime: updated(90*time.Minute + 30*time.Second), Stale: tenablesc.FakeFalse, UpdateRunning: tenablesc.FakeTrue},
+		"sc":      {UpdateTime: updated(40 * 24 * time.Hour), Stale: tenablesc.FakeTrue, UpdateRunning: tenablesc.FakeFalse},
+		"passive": {UpdateTime: "0", Stale: tenablesc.FakeTrue},
+		"lce":     {UpdateTime: "-1"},
+		"unset":   {},
+		"missing": nil,
+	}
+
+	got := make(map[string]float64)
+	for _, m := range feedMetrics(feeds, now) {
+		got[m.Key()] = m.Value
+	}
+	for key, want := range map[string]float64{
+		"feedAgeMinutes[feed:active]":    90,
+		"feedStale[feed:active]":         0,
+		"feedUpdateRunning[feed:active]": 1,
+		"feedAgeMinutes[feed:sc]":        40 * 24 * 60,
+		"feedStale[feed:sc]":             1,
+		"feedStale[feed:passive]":        1,
+		"feedUpdateRunning[feed:lce]":    0,
+		"feedStale[feed:unset]":          0,
+	} {
+		if value, ok := got[key]; !ok || value != want {
+			t.Errorf("%s = %v, want %v", key, value, want)
+		}
+	}
+	for _, key := range []string{"feedAgeMinutes[feed:passive]", "feedAgeMinutes[feed:lce]", "feedAgeMinutes[feed:unset]", "feedStale[feed:missing]"} {
+		if _, ok := got[key]; ok {
+			t.Errorf("%s is reported for a feed which was never updated", key)
+		}
+	}
+}
@@ -18,10 +18,57 @@ import (
 	"strconv"
 	"time"
 
+	"github.com/palantir/tenablesc-metrics/metrics"
 	"github.com/rs/zerolog/log"
 )
 
-func (c *Client) getJobMetrics() (global map[string]int64, jobtypes map[string]int64, err error) {
+func init() {
+	RegisterCollector(jobQueueCollectorName, true, newJobQueueCollector)
+}
+
+type jobQueueCollector struct {
+	// NotStartedGracePeriod is how far past its targeted time a job may be before it counts as not started.
+	NotStartedGracePeriod time.Duration `yaml:"notStartedGracePeriod"`
+}
+
+func newJobQueueCollector(cfg CollectorConfig) (Collector, error) {
+	// Buffering the 'now'; it's fine if jobs were targetted to start before now and haven't.
+	// it's not fine if they were targetted to start _waaaay_ sooner and haven't.
+	collector := &jobQueueCollector{
+		NotStartedGracePeriod: 5 * time.Minute,
+	}
+	if err := cfg.Decode(collector); err != nil {
+		return nil, err
+	}
+	return collector, nil
+}
+
+func (*jobQueueCollector) Name() string {
+	return jobQueueCollectorName
+}
+
+func (*jobQueueCollector) Scope() Scope {
+	return AdminScope
+}
+
+func (j *jobQueueCollector) Collect(t *Target) ([]metrics.Metric, error) {
+	var collected []metrics.Metric
+
+	globalJobMetrics, jobTypeMetrics, err := t.Client.getJobMetrics(j.NotStartedGracePeriod)
+	if err != nil {
+		return nil, err
+	}
+	for metricName, metric := range globalJobMetrics {
+		collected = append(collected, metrics.NewMetric(metricName, metrics.Gauge, float64(metric), nil))
+	}
+	for metricName, metric := range jobTypeMetrics {
+		collected = append(collected, metrics.NewMetric(jobQueueLengthMetricName, metrics.Gauge, float64(metric), map[string]string{jobTypeTagName: metricName}))
+	}
+
+	return collected, nil
+}
+
+func (c *Client) getJobMetrics(notStartedGracePeriod time.Duration) (global map[string]int64, jobtypes map[string]int64, err error) {
 
 	globalMetrics := make(map[string]int64)
 	jobTypeMetrics := make(map[string]int64)
@@ -34,9 +81,7 @@ func (c *Client) getJobMetrics() (global map[string]int64, jobtypes map[string]i
 	}
 	globalMetrics[jobQueueLengthMetricName] = int64(len(jobs))
 
-	// Buffering the 'now'; it's fine if jobs were targetted to start before now and haven't.
-	// it's not fine if they were targetted to start _waaaay_ sooner and haven't.
-	nowishEpoch := time.Now().Add(-5 * time.Minute).Unix()
+	nowishEpoch := time.Now().Add(-notStartedGracePeriod).Unix()
 
 	globalMetrics[jobsNotStartedMetricName] = 0
 	for _, job := range jobs {
@@ -0,0 +1,61 @@
+// Copyright 2022 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sc
+
+import (
+	"sync"
+
+	"github.com/palantir/tenablesc-metrics/metrics"
+)
+
+const (
+	defaultMaxConcurrency = 4
+)
+
+// collectionJob is one independent unit of a collection cycle, such as an admin collector or all collectors for an org.
+type collectionJob func() jobResult
+
+type jobResult struct {
+	metrics []metrics.Metric
+	failed  []metrics.Source
+	errs    []error
+}
+
+// runJobs runs the jobs with at most maxConcurrency in flight, returning their results in job order.
+func runJobs(jobs []collectionJob, maxConcurrency int) []jobResult {
+	results := make([]jobResult, len(jobs))
+	sem := make(chan struct{}, maxConcurrency)
+
+	var wg sync.WaitGroup
+	for i, job := range jobs {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, job collectionJob) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			results[i] = job()
+		}(i, job)
+	}
+	wg.Wait()
+
+	return results
+}
+
+func (c Config) maxConcurrency() int {
+	if c.MaxConcurrency <= 0 {
+		return defaultMaxConcurrency
+	}
+	return c.MaxConcurrency
+}
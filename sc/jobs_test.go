@@ -0,0 +1,89 @@
+// Copyright 2022 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sc
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/palantir/tenablesc-metrics/metrics"
+)
+
+func Test_runJobs(t *testing.T) {
+	const maxConcurrency = 3
+
+	var inFlight, maxInFlight int64
+	var jobs []collectionJob
+	for i := int64(0); i < 20; i++ {
+		i := i
+		jobs = append(jobs, func() jobResult {
+			n := atomic.AddInt64(&inFlight, 1)
+			defer atomic.AddInt64(&inFlight, -1)
+			for {
+				seen := atomic.LoadInt64(&maxInFlight)
+				if n <= seen || atomic.CompareAndSwapInt64(&maxInFlight, seen, n) {
+					break
+				}
+			}
+			time.Sleep(time.Millisecond)
+			return jobResult{metrics: []metrics.Metric{metrics.NewMetric("job", metrics.Gauge, float64(i), nil)}}
+		})
+	}
+
+	results := runJobs(jobs, maxConcurrency)
+
+	if maxInFlight > maxConcurrency {
+		t.Errorf("ran %d jobs at once, want at most %d", maxInFlight, maxConcurrency)
+	}
+	for i, result := range results {
+		if got := result.metrics[0].Value; got != float64(i) {
+			t.Errorf("result %d came from job %v", i, got)
+		}
+	}
+}
+
+type failingCollector string
+
+func (f failingCollector) Name() string { return string(f) }
+
+func (failingCollector) Scope() Scope { return OrgScope }
+
+func (failingCollector) Collect(*Target) ([]metrics.Metric, error) {
+	return nil, errors.New("failed")
+}
+
+func Test_orgFailures(t *testing.T) {
+	collectors := []Collector{failingCollector("a"), failingCollector("b"), failingCollector("c")}
+
+	for name, job := range map[string]collectionJob{
+		"login failed":      failedJob(collectors, "foo", errors.New("failed")),
+		"collectors failed": collectorsJob(collectors, &Target{Org: "foo"}),
+	} {
+		var orgFailures, collectorFailures float64
+		for _, m := range metrics.Merge(job().metrics) {
+			switch m.Name {
+			case orgFailuresMetricName:
+				orgFailures += m.Value
+			case collectorFailuresMetricName:
+				collectorFailures += m.Value
+			}
+		}
+		if orgFailures != 1 || collectorFailures != 3 {
+			t.Errorf("%s: orgFailures = %v and collectorFailures = %v, want 1 and 3", name, orgFailures, collectorFailures)
+		}
+	}
+}
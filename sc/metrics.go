@@ -15,106 +15,239 @@
 package sc
 
 import (
+	"errors"
 	"fmt"
-	"sort"
-	"strings"
+	"sync"
+	"time"
+
+	"github.com/palantir/tenablesc-metrics/metrics"
+	"github.com/rs/zerolog/log"
 )
 
-func buildTaggedMetricString(name string, tagMap map[string]string) string {
+// Collection gathers metrics from SC each cycle, and holds the state carried over between cycles.
+type Collection struct {
+	config      Config
+	collectors  []Collector
+	scanResults *scanResultState
+
+	orgNamesMu sync.Mutex
+	// orgNames maps org config names to the names SC last reported for them.
+	orgNames map[string]string
+
+	// recorder receives every scan result fetched for an org, if set.
+	recorder ScanResultRecorder
+}
+
+// NewCollection builds the enabled collectors and loads any persisted state.
+func (c Config) NewCollection() (*Collection, error) {
+	collectors, err := c.EnabledCollectors()
+	if err != nil {
+		return nil, err
+	}
+
+	scanResults, err := loadScanResultState(c.StateFile)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Collection{
+		config:      c,
+		collectors:  collectors,
+		scanResults: scanResults,
+		orgNames:    make(map[string]string),
+	}, nil
+}
+
+// SetScanResultRecorder passes every scan result fetched for an org on to recorder, from then on.
+func (c *Collection) SetScanResultRecorder(recorder ScanResultRecorder) {
+	c.recorder = recorder
+}
 
-	var tagStrings []string
+// GenerateMetricData runs every enabled collector and returns a snapshot of the metrics they produced.
+// Collectors and orgs fail independently: whatever was gathered is returned alongside an error joining every failure,
+// and each failure is counted in the collectorFailures and orgFailures metrics tagged with its error class.
+// The snapshot also records the sources which failed, so their missing series aren't mistaken for removed ones.
+func (c *Collection) GenerateMetricData() (*metrics.Snapshot, error) {
+	start := time.Now()
+
+	var adminCollectors, orgCollectors []Collector
+	for _, collector := range c.collectors {
+		switch collector.Scope() {
+		case AdminScope:
+			adminCollectors = append(adminCollectors, collector)
+		case OrgScope:
+			orgCollectors = append(orgCollectors, collector)
+		}
+	}
 
-	for k, v := range tagMap {
+	// Each job gathers into its own list; merging them in job order afterwards keeps the output deterministic.
+	var jobs []collectionJob
 
-		var tagValue string
-		if v == "" {
-			tagValue = noneTagValue
+	var adminClient *Client
+	var err error
+	if len(adminCollectors) > 0 {
+		adminClient, err = c.config.TenableAdminClient()
+		if err != nil {
+			err = fmt.Errorf("failed to create admin client: %w", err)
+			jobs = append(jobs, failedJob(adminCollectors, "", err))
 		} else {
-			tagValue = v
+			for _, collector := range adminCollectors {
+				jobs = append(jobs, collectorsJob([]Collector{collector}, &Target{Client: adminClient}))
+			}
 		}
-		tagStrings = append(tagStrings, fmt.Sprintf("%s:%s", k, tagValue))
 	}
-	sort.Strings(tagStrings)
 
-	if len(tagStrings) == 0 {
-		return name
+	if len(orgCollectors) > 0 {
+		for _, cfgOrgName := range c.config.TenableOrgNames() {
+			jobs = append(jobs, c.orgJob(cfgOrgName, orgCollectors))
+		}
 	}
 
-	return name + fmt.Sprintf("[%s]", strings.Join(tagStrings, ","))
+	results := runJobs(jobs, c.config.maxConcurrency())
+
+	var collected []metrics.Metric
+	var failed []metrics.Source
+	var errs []error
+	for _, result := range results {
+		collected = append(collected, result.metrics...)
+		failed = append(failed, result.failed...)
+		errs = append(errs, result.errs...)
+	}
+
+	// The admin client is shared between jobs, so its cache can only be reported once they have all finished.
+	if adminClient != nil {
+		collected = append(collected, adminClient.cacheMetrics("")...)
+	}
+
+	return metrics.NewSnapshot(start, collected, failed), errors.Join(errs...)
 }
 
-// GenerateMetricData generates a variety of metrics about SC and returns a map from metric name to value
-func (c Config) GenerateMetricData() (map[string]int64, error) {
-	metrics := make(map[string]int64)
+// orgJob logs into the org and runs each org collector against it in turn.
+func (c *Collection) orgJob(cfgOrgName string, collectors []Collector) collectionJob {
+	return func() jobResult {
+		target, err := c.orgTarget(cfgOrgName)
+		if err != nil {
+			err = fmt.Errorf("failed to create client for org %s: %w", cfgOrgName, err)
+			return failedJob(collectors, c.orgName(cfgOrgName), err)()
+		}
+
+		result := collectorsJob(collectors, target)()
+		result.metrics = append(result.metrics, target.Client.cacheMetrics(target.Org)...)
+		return result
+	}
+}
 
-	adminClient, err := c.TenableAdminClient()
+func (c *Collection) orgTarget(cfgOrgName string) (*Target, error) {
+	orgClient, err := c.config.TenableOrgClient(cfgOrgName)
 	if err != nil {
 		return nil, err
 	}
+	// Keyed by the config name, as that is stable even if the org is renamed.
+	orgClient.scanResults = c.scanResults.forOrg(cfgOrgName)
 
-	scannerStatus, err := adminClient.getScannerStatus()
+	user, err := orgClient.GetCurrentUser()
 	if err != nil {
 		return nil, err
 	}
 
-	metrics[buildTaggedMetricString(healthyScannerCountMetricName, nil)] = scannerStatus.Unhealthy
-	metrics[buildTaggedMetricString(unhealthyScannerCountMetricName, nil)] = scannerStatus.Unhealthy
-	metrics[buildTaggedMetricString(totalScannerCountMetricName, nil)] = scannerStatus.Total
+	c.orgNamesMu.Lock()
+	c.orgNames[cfgOrgName] = user.OrgName
+	c.orgNamesMu.Unlock()
 
-	for zone, status := range scannerStatus.ByZoneName {
-		metrics[buildTaggedMetricString(healthyScannerCountMetricName, map[string]string{scanZoneTagName: zone})] = status.Healthy
-		metrics[buildTaggedMetricString(unhealthyScannerCountMetricName, map[string]string{scanZoneTagName: zone})] = status.Unhealthy
-		metrics[buildTaggedMetricString(totalScannerCountMetricName, map[string]string{scanZoneTagName: zone})] = status.Total
-	}
+	orgClient.org = user.OrgName
+	orgClient.recorder = c.recorder
 
-	globalJobMetrics, jobTypeMetrics, err := adminClient.getJobMetrics()
-	if err != nil {
-		return nil, err
-	}
-	for metricName, metric := range globalJobMetrics {
-		metrics[buildTaggedMetricString(metricName, nil)] = metric
-	}
-	for metricName, metric := range jobTypeMetrics {
-		metrics[buildTaggedMetricString(jobQueueLengthMetricName, map[string]string{jobTypeTagName: metricName})] = metric
-	}
+	return &Target{Client: orgClient, Org: user.OrgName}, nil
+}
 
-	for _, cfgOrgName := range c.TenableOrgNames() {
-		orgClient, err := c.TenableOrgClient(cfgOrgName)
-		if err != nil {
-			return nil, err
-		}
+// orgName returns the name SC last reported for the org, so failures are tagged the same way as its metrics.
+// If we have never logged into the org, the config key has to stand in for it.
+func (c *Collection) orgName(cfgOrgName string) string {
+	c.orgNamesMu.Lock()
+	defer c.orgNamesMu.Unlock()
 
-		user, err := orgClient.GetCurrentUser()
-		if err != nil {
-			return nil, err
-		}
-		orgName := user.OrgName
+	if name, ok := c.orgNames[cfgOrgName]; ok {
+		return name
+	}
+	return cfgOrgName
+}
 
-		scanAges, err := orgClient.getScheduledActiveScanAges()
-		if err != nil {
-			return nil, err
+// collectorsJob runs each collector against the target in turn.
+func collectorsJob(collectors []Collector, target *Target) collectionJob {
+	return func() jobResult {
+		var result jobResult
+		var orgFailure error
+		for _, collector := range collectors {
+			log := log.With().Str("collector", collector.Name()).Str("org", target.Org).Logger()
+			log.Debug().Msg("running collector")
+
+			data, err := collector.Collect(target)
+			for _, m := range data {
+				m.Collector = collector.Name()
+				m.Org = target.Org
+				result.metrics = append(result.metrics, m)
+			}
+			if err != nil {
+				log.Err(err).Int("partialMetrics", len(data)).Msg("collector failed")
+				err = fmt.Errorf("collector %s failed for org %q: %w", collector.Name(), target.Org, err)
+				result.metrics = append(result.metrics, failureMetrics([]Collector{collector}, target.Org, err)...)
+				result.failed = append(result.failed, failedSources([]Collector{collector}, target.Org)...)
+				result.errs = append(result.errs, err)
+				if orgFailure == nil && collector.Scope() == OrgScope {
+					orgFailure = err
+				}
+			}
 		}
-		for scanName, metric := range scanAges {
-			metrics[buildTaggedMetricString(minutesSinceLastScanMetricName, map[string]string{orgTagName: orgName, scanNameTagName: scanName})] = metric
+		if orgFailure != nil {
+			result.metrics = append(result.metrics, orgFailureMetric(target.Org, orgFailure))
 		}
+		return result
+	}
+}
 
-		scanDurations, err := orgClient.getScheduledActiveScanDurations()
-		if err != nil {
-			return nil, err
+// failedJob reports err against every collector without running any of them.
+func failedJob(collectors []Collector, org string, err error) collectionJob {
+	return func() jobResult {
+		failures := failureMetrics(collectors, org, err)
+		for _, collector := range collectors {
+			if collector.Scope() == OrgScope {
+				failures = append(failures, orgFailureMetric(org, err))
+				break
+			}
 		}
-		for scanName, metric := range scanDurations {
-			metrics[buildTaggedMetricString(scanDurationSecondsMetricName, map[string]string{orgTagName: orgName, scanNameTagName: scanName})] = metric
+		return jobResult{
+			metrics: failures,
+			failed:  failedSources(collectors, org),
+			errs:    []error{err},
 		}
+	}
+}
 
-		assetIPCounts, err := orgClient.getAssetIPCounts()
-		if err != nil {
-			return nil, err
-		}
-		for assetName, metric := range assetIPCounts {
-			metrics[buildTaggedMetricString(ipCountMetricName, map[string]string{orgTagName: orgName, assetNameTagName: assetName})] = metric
-		}
+// failureMetrics counts err against each of the collectors.
+// They are counters, so merging sums them per series.
+func failureMetrics(collectors []Collector, org string, err error) []metrics.Metric {
+	class := errorClass(err)
 
+	var failures []metrics.Metric
+	for _, collector := range collectors {
+		failures = append(failures, metrics.NewMetric(collectorFailuresMetricName, metrics.Counter, 1, map[string]string{collectorTagName: collector.Name(), orgTagName: org, errorClassTagName: class}))
 	}
+	return failures
+}
+
+// orgFailureMetric counts a failed org job once, however many of its collectors failed; err is the first failure.
+func orgFailureMetric(org string, err error) metrics.Metric {
+	return metrics.NewMetric(orgFailuresMetricName, metrics.Counter, 1, map[string]string{orgTagName: org, errorClassTagName: errorClass(err)})
+}
 
-	return metrics, nil
+func failedSources(collectors []Collector, org string) []metrics.Source {
+	if org == "" {
+		org = metrics.NoneTagValue
+	}
+
+	var sources []metrics.Source
+	for _, collector := range collectors {
+		sources = append(sources, metrics.Source{Collector: collector.Name(), Org: org})
+	}
+	return sources
 }
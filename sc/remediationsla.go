@@ -0,0 +1,250 @@
+// Copyright 2022 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sc
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"time"
+
+	"github.com/palantir/tenablesc-client/tenablesc"
+	"github.com/palantir/tenablesc-metrics/metrics"
+	"github.com/rs/zerolog/log"
+)
+
+const day = 24 * time.Hour
+
+func init() {
+	// Disabled by default: vulndetails returns every finding, which is far more than any other collector fetches.
+	RegisterCollector(remediationSLACollectorName, false, newRemediationSLACollector)
+}
+
+// remediationSLACollector reports how far behind on remediation each org is: how many open findings of each
+// severity are older than its SLA, and how the ages of open findings are spread.
+type remediationSLACollector struct {
+	// SLAs is how long a finding of each severity may stay open, by severity name. Configured SLAs replace the
+	// defaults for their severities, and zero turns a severity's SLA off.
+	SLAs map[string]time.Duration `yaml:"slas"`
+	// AgeBuckets are the upper bounds of the age buckets, in increasing order; the last bucket holds everything older.
+	AgeBuckets []time.Duration `yaml:"ageBuckets"`
+	// MaxLastSeenAge skips findings not seen by a scan for longer than this, as they are probably gone but not yet
+	// mitigated; zero counts every open finding.
+	MaxLastSeenAge time.Duration `yaml:"maxLastSeenAge"`
+	// Assets are glob patterns for the asset lists counts are also reported for; by default every asset list.
+	Assets   []string `yaml:"assets"`
+	PageSize int      `yaml:"pageSize"`
+}
+
+func newRemediationSLACollector(cfg CollectorConfig) (Collector, error) {
+	collector := &remediationSLACollector{
+		AgeBuckets: []time.Duration{7 * day, 15 * day, 30 * day, 60 * day, 90 * day, 180 * day, 365 * day},
+		Assets:     []string{"*"},
+		PageSize:   defaultAnalysisPageSize,
+	}
+	if err := cfg.Decode(collector); err != nil {
+		return nil, err
+	}
+
+	// Decoding into a map which already has the defaults would reject them as duplicate keys, so merge afterwards.
+	slas := map[string]time.Duration{
+		"critical": 15 * day,
+		"high":     30 * day,
+		"medium":   90 * day,
+		"low":      180 * day,
+	}
+	for severity, sla := range collector.SLAs {
+		if severityIndexByName(severity) < 0 {
+			return nil, fmt.Errorf("unknown severity %q in slas", severity)
+		}
+		slas[severity] = sla
+	}
+	collector.SLAs = slas
+	if len(collector.AgeBuckets) == 0 || !sort.SliceIsSorted(collector.AgeBuckets, func(i, j int) bool { return collector.AgeBuckets[i] < collector.AgeBuckets[j] }) {
+		return nil, fmt.Errorf("ageBuckets must be set in increasing order")
+	}
+	for _, pattern := range collector.Assets {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("bad asset pattern %q: %w", pattern, err)
+		}
+	}
+	if collector.PageSize <= 0 {
+		collector.PageSize = defaultAnalysisPageSize
+	}
+	return collector, nil
+}
+
+func (*remediationSLACollector) Name() string {
+	return remediationSLACollectorName
+}
+
+func (*remediationSLACollector) Scope() Scope {
+	return OrgScope
+}
+
+func (r *remediationSLACollector) Collect(t *Target) ([]metrics.Metric, error) {
+	now := time.Now()
+	var collected []metrics.Metric
+
+	byRepository, err := r.findingAges(t.Client, nil, now)
+	if err != nil {
+		return nil, err
+	}
+	for repository, ages := range byRepository {
+		collected = append(collected, ages.metrics(slaBreachCountMetricName, openFindingsByAgeMetricName, map[string]string{orgTagName: t.Org, repositoryTagName: repository})...)
+	}
+
+	assets, err := t.Client.GetAllAssets()
+	if err != nil {
+		return collected, err
+	}
+
+	var errs []error
+	for _, asset := range assets {
+		if !matchesAnyPattern(r.Assets, asset.Name) {
+			continue
+		}
+
+		filter := tenablesc.AnalysisFilter{FilterName: "asset", Operator: "=", Value: tenablesc.BaseInfo{ID: asset.ID}}
+		byRepository, err := r.findingAges(t.Client, []tenablesc.AnalysisFilter{filter}, now)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to get findings of asset %s: %w", asset.Name, err))
+			continue
+		}
+
+		total := r.newFindingAges()
+		for _, ages := range byRepository {
+			total.add(ages)
+		}
+		collected = append(collected, total.metrics(assetSLABreachCountMetricName, assetOpenFindingsByAgeMetricName, map[string]string{orgTagName: t.Org, assetNameTagName: asset.Name})...)
+	}
+
+	return collected, errors.Join(errs...)
+}
+
+// findingAges tallies every open finding matching the filters, other than informational ones, by repository name.
+// Findings which can't be tallied are logged and left out, so one odd finding doesn't lose the org's counts.
+func (r *remediationSLACollector) findingAges(c *Client, filters []tenablesc.AnalysisFilter, now time.Time) (map[string]*findingAges, error) {
+	filters = append([]tenablesc.AnalysisFilter{{FilterName: "severity", Operator: "=", Value: "1,2,3,4"}}, filters...)
+
+	byRepository := make(map[string]*findingAges)
+	var skipped int
+	var firstErr error
+	err := analyzePages(c, "vulndetails", filters, r.PageSize, func(findings []tenablesc.VulnDetailsResult) error {
+		for _, finding := range findings {
+			ages, ok := byRepository[finding.Repository.Name]
+			if !ok {
+				ages = r.newFindingAges()
+				byRepository[finding.Repository.Name] = ages
+			}
+			if err := r.tally(ages, finding, now); err != nil {
+				if skipped++; firstErr == nil {
+					firstErr = err
+				}
+			}
+		}
+		return nil
+	})
+	if skipped > 0 {
+		log.Warn().Err(firstErr).Int("skipped", skipped).Msg("skipped findings which couldn't be tallied")
+	}
+	return byRepository, err
+}
+
+// tally counts a single finding's age, and whether it breaches its severity's SLA.
+func (r *remediationSLACollector) tally(ages *findingAges, finding tenablesc.VulnDetailsResult, now time.Time) error {
+	severity, ok := severityIndex(string(finding.Severity.ID))
+	if !ok {
+		return fmt.Errorf("finding of plugin %s on %s has unknown severity %q", finding.PluginID, finding.IP, finding.Severity.ID)
+	}
+	firstSeen := epochStringToTime(finding.FirstSeen)
+	if firstSeen.IsZero() {
+		return fmt.Errorf("finding of plugin %s on %s has no first seen time", finding.PluginID, finding.IP)
+	}
+	if r.MaxLastSeenAge > 0 {
+		lastSeen := epochStringToTime(finding.LastSeen)
+		if lastSeen.IsZero() {
+			return fmt.Errorf("finding of plugin %s on %s has no last seen time", finding.PluginID, finding.IP)
+		}
+		if now.Sub(lastSeen) > r.MaxLastSeenAge {
+			return nil
+		}
+	}
+
+	age := now.Sub(firstSeen)
+	if sla := r.SLAs[severities[severity]]; sla > 0 && age > sla {
+		ages.breached[severity]++
+	}
+	bucket := sort.Search(len(r.AgeBuckets), func(i int) bool { return age < r.AgeBuckets[i] })
+	ages.byBucket[bucket][severity]++
+	return nil
+}
+
+func (r *remediationSLACollector) newFindingAges() *findingAges {
+	ages := &findingAges{byBucket: make([]severityCounts, len(r.AgeBuckets)+1)}
+	// Bucket names are the range of ages in whole days, such as 7-15d; the last one is open ended, such as 365d+.
+	lower := 0
+	for _, bound := range r.AgeBuckets {
+		upper := int(bound / day)
+		ages.bucketNames = append(ages.bucketNames, fmt.Sprintf("%d-%dd", lower, upper))
+		lower = upper
+	}
+	ages.bucketNames = append(ages.bucketNames, fmt.Sprintf("%dd+", lower))
+	return ages
+}
+
+// findingAges counts open findings by severity, both in total past their SLA and by age bucket.
+type findingAges struct {
+	breached    severityCounts
+	byBucket    []severityCounts
+	bucketNames []string
+}
+
+func (f *findingAges) add(other *findingAges) {
+	f.breached.add(other.breached)
+	for i := range f.byBucket {
+		f.byBucket[i].add(other.byBucket[i])
+	}
+}
+
+// metrics reports the counts of every severity but info, as informational findings aren't queried.
+func (f *findingAges) metrics(breachName, byAgeName string, tags map[string]string) []metrics.Metric {
+	all := f.breached.metrics(breachName, tags)
+	for i, counts := range f.byBucket {
+		bucketTags := map[string]string{ageBucketTagName: f.bucketNames[i]}
+		for k, v := range tags {
+			bucketTags[k] = v
+		}
+		all = append(all, counts.metrics(byAgeName, bucketTags)...)
+	}
+
+	var collected []metrics.Metric
+	for _, m := range all {
+		if m.Tags[severityTagName] != "info" {
+			collected = append(collected, m)
+		}
+	}
+	return collected
+}
+
+func severityIndexByName(name string) int {
+	for i, severity := range severities {
+		if severity == name {
+			return i
+		}
+	}
+	return -1
+}
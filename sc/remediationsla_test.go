@@ -0,0 +1,152 @@
+// Copyright 2022 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sc
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/palantir/tenablesc-client/tenablesc"
+	"gopkg.in/yaml.v2"
+)
+
+func Test_remediationSLACollector_tally(t *testing.T) {
+	var c Config
+	config := `
+collectors:
+  remediationSLA:
+    slas:
+      high: 240h
+    ageBuckets: [168h, 720h]
+    maxLastSeenAge: 720h
+`
+	if err := yaml.UnmarshalStrict([]byte(config), &c); err != nil {
+		t.Fatalf("failed to parse config: %v", err)
+	}
+	collector, err := newRemediationSLACollector(c.Collectors[remediationSLACollectorName])
+	if err != nil {
+		t.Fatalf("newRemediationSLACollector() error = %v", err)
+	}
+	r := collector.(*remediationSLACollector)
+
+	now := time.Unix(1700000000, 0)
+	finding := func(severity string, age, lastSeen time.Duration) tenablesc.VulnDetailsResult {
+		return tenablesc.VulnDetailsResult{
+			Severity:  tenablesc.BaseInfo{ID: tenablesc.ProbablyString(severity)},
+			FirstSeen: strconv.FormatInt(now.Add(-age).Unix(), 10),
+			LastSeen:  strconv.FormatInt(now.Add(-lastSeen).Unix(), 10),
+		}
+	}
+
+	ages := r.newFindingAges()
+	for _, f := range []tenablesc.VulnDetailsResult{
+		finding("4", 14*day, 0),      // critical, within the default 15 day SLA
+		finding("4", 16*day, day),    // critical, past it
+		finding("3", 11*day, 0),      // high, past the configured 10 day SLA
+		finding("3", 3*day, 0),       // high, within it
+		finding("1", 400*day, 0),     // low, past the default 180 day SLA
+		finding("3", 60*day, 40*day), // not seen for too long to count
+	} {
+		if err := r.tally(ages, f, now); err != nil {
+			t.Fatalf("tally() error = %v", err)
+		}
+	}
+
+	noLastSeen := finding("4", day, 0)
+	noLastSeen.LastSeen = ""
+	if err := r.tally(ages, noLastSeen, now); err == nil {
+		t.Errorf("tally() of a finding without a last seen time succeeded")
+	}
+
+	got := make(map[string]float64)
+	for _, m := range ages.metrics(slaBreachCountMetricName, openFindingsByAgeMetricName, nil) {
+		got[m.Key()] = m.Value
+	}
+	for key, want := range map[string]float64{
+		"slaBreachCount[severity:critical]":                    1,
+		"slaBreachCount[severity:high]":                        1,
+		"slaBreachCount[severity:low]":                         1,
+		"openFindingsByAge[ageBucket:0-7d,severity:high]":      1,
+		"openFindingsByAge[ageBucket:7-30d,severity:critical]": 2,
+		"openFindingsByAge[ageBucket:7-30d,severity:high]":     1,
+		"openFindingsByAge[ageBucket:30d+,severity:low]":       1,
+		"openFindingsByAge[ageBucket:30d+,severity:high]":      0,
+	} {
+		if got[key] != want {
+			t.Errorf("%s = %v, want %v", key, got[key], want)
+		}
+	}
+	if _, ok := got["slaBreachCount[severity:info]"]; ok {
+		t.Errorf("info findings are reported, but never queried")
+	}
+}
+
+func Test_remediationSLACollector_findingAges(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	firstSeen := strconv.FormatInt(now.Add(-20*day).Unix(), 10)
+	findings := []tenablesc.VulnDetailsResult{
+		{Severity: tenablesc.BaseInfo{ID: "4"}, FirstSeen: firstSeen, Repository: tenablesc.VulnRepository{Name: "prod"}},
+		{Severity: tenablesc.BaseInfo{ID: "3"}, FirstSeen: firstSeen, Repository: tenablesc.VulnRepository{Name: "prod"}},
+		// skipped, rather than failing the counts of every other finding
+		{Severity: tenablesc.BaseInfo{ID: "3"}, Repository: tenablesc.VulnRepository{Name: "prod"}},
+		{Severity: tenablesc.BaseInfo{ID: "2"}, FirstSeen: firstSeen, Repository: tenablesc.VulnRepository{Name: "dev"}},
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var analysis tenablesc.Analysis
+		if err := json.NewDecoder(r.Body).Decode(&analysis); err != nil || analysis.Query.Tool != "vulndetails" {
+			t.Errorf("unexpected analysis request %+v: %v", analysis, err)
+		}
+		start, _ := strconv.Atoi(analysis.StartOffset)
+		end, _ := strconv.Atoi(analysis.EndOffset)
+		start, end = min(start, len(findings)), min(end, len(findings))
+		page, _ := json.Marshal(findings[start:end])
+		response, _ := json.Marshal(tenablesc.AnalysisResponseContainer{TotalRecords: strconv.Itoa(len(findings)), Results: page})
+		_ = json.NewEncoder(w).Encode(tenablesc.SCResponse{Response: response})
+	}))
+	defer server.Close()
+
+	collector, err := newRemediationSLACollector(CollectorConfig{})
+	if err != nil {
+		t.Fatalf("newRemediationSLACollector() error = %v", err)
+	}
+	r := collector.(*remediationSLACollector)
+	r.PageSize = 3
+
+	byRepository, err := r.findingAges(&Client{Client: *tenablesc.NewClient(server.URL)}, nil, now)
+	if err != nil {
+		t.Fatalf("findingAges() error = %v", err)
+	}
+	got := make(map[string]float64)
+	for repository, ages := range byRepository {
+		for _, m := range ages.metrics(slaBreachCountMetricName, openFindingsByAgeMetricName, map[string]string{repositoryTagName: repository}) {
+			got[m.Key()] = m.Value
+		}
+	}
+	for key, want := range map[string]float64{
+		"slaBreachCount[repository:prod,severity:critical]":                     1,
+		"slaBreachCount[repository:prod,severity:high]":                         0,
+		"openFindingsByAge[ageBucket:15-30d,repository:prod,severity:high]":     1,
+		"openFindingsByAge[ageBucket:15-30d,repository:dev,severity:medium]":    1,
+		"openFindingsByAge[ageBucket:15-30d,repository:prod,severity:critical]": 1,
+	} {
+		if got[key] != want {
+			t.Errorf("%s = %v, want %v", key, got[key], want)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+// Copyright 2022 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sc
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/palantir/tenablesc-client/tenablesc"
+	"github.com/palantir/tenablesc-metrics/metrics"
+	"github.com/rs/zerolog/log"
+)
+
+// repositoryFields are requested from SC's repository endpoint, which otherwise only returns ids and names.
+const repositoryFields = "id,name,description,type,dataFormat,downloadFormat,running,organizations,createdTime,modifiedTime,typeFields"
+
+func init() {
+	RegisterCollector(repositoriesCollectorName, true, newRepositoriesCollector)
+}
+
+// repositoriesCollector reports the size and ingestion state of each repository. A repository which stopped
+// importing looks just like a scan which found nothing, so this is where "scan finished but data missing" shows.
+type repositoriesCollector struct{}
+
+func newRepositoriesCollector(cfg CollectorConfig) (Collector, error) {
+	return repositoriesCollector{}, cfg.Decode(&struct{}{})
+}
+
+func (repositoriesCollector) Name() string {
+	return repositoriesCollectorName
+}
+
+func (repositoriesCollector) Scope() Scope {
+	return AdminScope
+}
+
+func (repositoriesCollector) Collect(t *Target) ([]metrics.Metric, error) {
+	repositories, err := t.Client.GetAllRepositories()
+	if err != nil {
+		return nil, err
+	}
+	orgs, err := t.Client.GetAllOrganizations()
+	if err != nil {
+		return nil, err
+	}
+	orgNames := make(map[string]string, len(orgs))
+	for _, org := range orgs {
+		orgNames[string(org.ID)] = org.Name
+	}
+
+	return repositoriesMetrics(repositories, orgNames, time.Now()), nil
+}
+
+func repositoriesMetrics(repositories []*tenablesc.Repository, orgNames map[string]string, now time.Time) []metrics.Metric {
+	var collected []metrics.Metric
+	for _, repository := range repositories {
+		// Repositories shared between orgs are reported once for each, so they show up under every org using them.
+		repoOrgs := []string{""}
+		if len(repository.Organizations) > 0 {
+			repoOrgs = repoOrgs[:0]
+			for _, org := range repository.Organizations {
+				name, ok := orgNames[org.ID]
+				if !ok {
+					name = org.ID
+				}
+				repoOrgs = append(repoOrgs, name)
+			}
+		}
+
+		for _, org := range repoOrgs {
+			collected = append(collected, repositoryMetrics(repository, map[string]string{repositoryTagName: repository.Name, orgTagName: org}, now)...)
+		}
+	}
+	return collected
+}
+
+func repositoryMetrics(repository *tenablesc.Repository, tags map[string]string, now time.Time) []metrics.Metric {
+	collected := []metrics.Metric{
+		metrics.NewMetric(repositoryRunningMetricName, metrics.Gauge, boolValue(repository.Running.AsBool()), tags),
+	}
+
+	// Only IP repositories have these; agent repositories leave them empty.
+	if ipCount, err := strconv.ParseInt(repository.IPCount, 10, 64); err == nil {
+		collected = append(collected, metrics.NewMetric(repositoryIPCountMetricName, metrics.Gauge, float64(ipCount), tags))
+	}
+	if generated, err := repository.LastGenerateNessusTime.ToDateTime(); err == nil && generated.Unix() > 0 {
+		collected = append(collected, metrics.NewMetric(repositoryGenerateAgeMetricName, metrics.Gauge, float64(int64(now.Sub(generated).Minutes())), tags).WithUnit(minuteUnit))
+	}
+
+	for vulnType, lifetime := range map[string]string{
+		"active":     repository.ActiveVulnsLifetime,
+		"passive":    repository.PassiveVulnsLifetime,
+		"lce":        repository.LCEVulnsLifetime,
+		"compliance": repository.ComplianceVulnsLifetime,
+		"mitigated":  repository.MitigatedVulnsLifetime,
+	} {
+		days, err := strconv.ParseInt(lifetime, 10, 64)
+		if err != nil {
+			continue
+		}
+		lifetimeTags := map[string]string{vulnTypeTagName: vulnType}
+		for k, v := range tags {
+			lifetimeTags[k] = v
+		}
+		collected = append(collected, metrics.NewMetric(repositoryVulnLifetimeMetricName, metrics.Gauge, float64(days), lifetimeTags).WithUnit(dayUnit))
+	}
+
+	return collected
+}
+
+// fetchRepositories lists the repositories without the SC client, which fails on the first repository that isn't
+// local or holds mobile data. Repositories are decoded one by one instead: the type specific fields are kept where
+// they decode, and a repository which can't be decoded at all is skipped so the rest are still reported.
+func (c *Client) fetchRepositories() ([]*tenablesc.Repository, error) {
+	var raw []json.RawMessage
+	if err := c.request(http.MethodGet, "/repository", url.Values{"fields": {repositoryFields}}, nil, &raw); err != nil {
+		return nil, fmt.Errorf("failed to get repositories: %w", err)
+	}
+	return decodeRepositories(raw), nil
+}
+
+func decodeRepositories(raw []json.RawMessage) []*tenablesc.Repository {
+	repositories := make([]*tenablesc.Repository, 0, len(raw))
+	for _, r := range raw {
+		var decoded struct {
+			tenablesc.RepoBaseFields
+			TypeFields json.RawMessage `json:"typeFields"`
+		}
+		if err := json.Unmarshal(r, &decoded); err != nil {
+			log.Warn().Err(err).Msg("skipping a repository which couldn't be decoded")
+			continue
+		}
+
+		repository := &tenablesc.Repository{RepoBaseFields: decoded.RepoBaseFields}
+		if len(decoded.TypeFields) > 0 {
+			if err := json.Unmarshal(decoded.TypeFields, &repository.RepoFieldsCommon); err != nil {
+				log.Debug().Err(err).Str("repository", repository.Name).Str("type", repository.Type).Msg("ignoring repository fields which couldn't be decoded")
+			}
+			if err := json.Unmarshal(decoded.TypeFields, &repository.RepoIPFields); err != nil {
+				log.Debug().Err(err).Str("repository", repository.Name).Str("type", repository.Type).Msg("ignoring repository fields which couldn't be decoded")
+			}
+		}
+		repositories = append(repositories, repository)
+	}
+	return repositories
+}
@@ -0,0 +1,127 @@
+// Copyright 2022 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sc
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/palantir/tenablesc-client/tenablesc"
+)
+
+func Test_repositoriesMetrics(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+
+	repository := func(name, generated string, orgIDs ...string) *tenablesc.Repository {
+		r := &tenablesc.Repository{}
+		r.Name = name
+		r.Running = tenablesc.FakeFalse
+		r.IPCount = "42"
+		r.ActiveVulnsLifetime = "365"
+		r.LastGenerateNessusTime = tenablesc.UnixEpochStringTime(generated)
+		for _, id := range orgIDs {
+			r.Organizations = append(r.Organizations, tenablesc.RepoOrganization{ID: id})
+		}
+		return r
+	}
+	agent := &tenablesc.Repository{}
+	agent.Name = "agents"
+	agent.Running = tenablesc.FakeTrue
+	agent.ActiveVulnsLifetime = "30"
+	agent.Organizations = []tenablesc.RepoOrganization{{ID: "1"}}
+
+	collected := repositoriesMetrics([]*tenablesc.Repository{
+		repository("shared", strconv.FormatInt(now.Add(-2*time.Hour).Unix(), 10), "1", "2", "3"),
+		repository("orphan", "-1"),
+		agent,
+	}, map[string]string{"1": "alpha", "2": "beta"}, now)
+
+	var got []string
+	values := make(map[string]float64)
+	for _, m := range collected {
+		got = append(got, m.Key())
+		values[m.Key()] = m.Value
+	}
+	sort.Strings(got)
+
+	want := []string{
+		"repositoryGenerateAgeMinutes[org:3,repository:shared]",
+		"repositoryGenerateAgeMinutes[org:alpha,repository:shared]",
+		"repositoryGenerateAgeMinutes[org:beta,repository:shared]",
+		"repositoryIPCount[org:3,repository:shared]",
+		"repositoryIPCount[org:alpha,repository:shared]",
+		"repositoryIPCount[org:beta,repository:shared]",
+		"repositoryIPCount[org:none,repository:orphan]",
+		"repositoryRunning[org:3,repository:shared]",
+		"repositoryRunning[org:alpha,repository:agents]",
+		"repositoryRunning[org:alpha,repository:shared]",
+		"repositoryRunning[org:beta,repository:shared]",
+		"repositoryRunning[org:none,repository:orphan]",
+		"repositoryVulnLifetimeDays[org:3,repository:shared,vulnType:active]",
+		"repositoryVulnLifetimeDays[org:alpha,repository:agents,vulnType:active]",
+		"repositoryVulnLifetimeDays[org:alpha,repository:shared,vulnType:active]",
+		"repositoryVulnLifetimeDays[org:beta,repository:shared,vulnType:active]",
+		"repositoryVulnLifetimeDays[org:none,repository:orphan,vulnType:active]",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("repositoriesMetrics() = %v, want %v", got, want)
+	}
+	if got := values["repositoryGenerateAgeMinutes[org:alpha,repository:shared]"]; got != 120 {
+		t.Errorf("repositoryGenerateAgeMinutes = %v, want 120", got)
+	}
+	if got := values["repositoryRunning[org:alpha,repository:agents]"]; got != 1 {
+		t.Errorf("repositoryRunning = %v, want 1", got)
+	}
+	if got := values["repositoryVulnLifetimeDays[org:alpha,repository:agents,vulnType:active]"]; got != 30 {
+		t.Errorf("repositoryVulnLifetimeDays = %v, want 30", got)
+	}
+}
+
+func TestClient_fetchRepositories(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repository" || r.URL.Query().Get("fields") != repositoryFields || r.Header.Get("x-apikey") != "accesskey=a; secretkey=b;" {
+			http.Error(w, `{"error_code": 1, "error_msg": "unexpected request"}`, http.StatusBadRequest)
+			return
+		}
+		_, _ = w.Write([]byte(`{"error_code": 0, "response": [
+			{"id": "1", "name": "local", "type": "Local", "dataFormat": "IPv4", "running": "false", "typeFields": {"ipCount": "42", "activeVulnsLifetime": "365"}},
+			{"id": "2", "name": "remote", "type": "Remote", "dataFormat": "IPv4", "running": "true", "typeFields": {"remoteSC": {"host": "sc2.local"}}},
+			{"id": "3", "name": "phones", "type": "Local", "dataFormat": "mobile", "running": "false", "typeFields": {"activeVulnsLifetime": 7}},
+			{"id": "4", "name": "broken", "organizations": "none"}
+		]}`))
+	}))
+	defer server.Close()
+
+	client := &Client{cache: newResponseCache(), url: server.URL, apiKey: "accesskey=a; secretkey=b;", httpClient: server.Client()}
+	repositories, err := client.GetAllRepositories()
+	if err != nil {
+		t.Fatalf("GetAllRepositories() error = %v", err)
+	}
+
+	var got []string
+	for _, r := range repositories {
+		got = append(got, r.Name+":"+r.Type+":"+r.IPCount+":"+r.ActiveVulnsLifetime)
+	}
+	// the remote and mobile repositories the SC client rejects are kept, and only the undecodable one is skipped.
+	want := []string{"local:Local:42:365", "remote:Remote::", "phones:Local::"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GetAllRepositories() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,256 @@
+// Copyright 2022 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sc
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/palantir/tenablesc-client/tenablesc"
+	"github.com/palantir/tenablesc-metrics/metrics"
+)
+
+const (
+	acceptRuleType = "accept"
+	recastRuleType = "recast"
+)
+
+func init() {
+	RegisterCollector(riskRulesCollectorName, true, newRiskRulesCollector)
+}
+
+// riskRulesCollector reports the hygiene of each org's accept risk and recast risk rules: how many there are, how
+// many never expire, are about to or already have, and how many findings they hide by severity. Optionally it also
+// reports how many findings each rule applies to.
+type riskRulesCollector struct {
+	// ExpiringWithin is how soon a rule has to expire to count as expiring soon.
+	ExpiringWithin time.Duration `yaml:"expiringWithin"`
+	// CountFindings reports the findings each rule applies to, at the cost of an analysis query per rule.
+	CountFindings bool `yaml:"countFindings"`
+}
+
+func newRiskRulesCollector(cfg CollectorConfig) (Collector, error) {
+	collector := &riskRulesCollector{
+		ExpiringWithin: 30 * day,
+	}
+	if err := cfg.Decode(collector); err != nil {
+		return nil, err
+	}
+	return collector, nil
+}
+
+func (*riskRulesCollector) Name() string {
+	return riskRulesCollectorName
+}
+
+func (*riskRulesCollector) Scope() Scope {
+	return OrgScope
+}
+
+func (r *riskRulesCollector) Collect(t *Target) ([]metrics.Metric, error) {
+	acceptRules, err := t.Client.GetAllAcceptRiskRules()
+	if err != nil {
+		return nil, err
+	}
+	recastRules, err := t.Client.GetAllRecastRiskRules()
+	if err != nil {
+		return nil, err
+	}
+
+	var rules []riskRule
+	for _, rule := range acceptRules {
+		rules = append(rules, acceptRiskRule(rule))
+	}
+	for _, rule := range recastRules {
+		rules = append(rules, recastRiskRule(rule))
+	}
+
+	collected := riskRuleHygiene(rules, time.Now(), r.ExpiringWithin, t.Org)
+
+	var errs []error
+	for _, ruleType := range []string{acceptRuleType, recastRuleType} {
+		counts, err := t.Client.severitySummary([]tenablesc.AnalysisFilter{riskRuleStatusFilter(ruleType)})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed counting findings suppressed by %s risk rules: %w", ruleType, err))
+			continue
+		}
+		collected = append(collected, counts.metrics(suppressedFindingsMetricName, map[string]string{orgTagName: t.Org, ruleTypeTagName: ruleType})...)
+	}
+	if !r.CountFindings {
+		return collected, errors.Join(errs...)
+	}
+
+	for _, rule := range rules {
+		findings, err := t.Client.riskRuleFindings(rule)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed counting findings of %s risk rule %s: %w", rule.ruleType, rule.id, err))
+			continue
+		}
+		collected = append(collected, metrics.NewMetric(riskRuleFindingsMetricName, metrics.Gauge, float64(findings), map[string]string{
+			orgTagName:        t.Org,
+			ruleTypeTagName:   rule.ruleType,
+			ruleIDTagName:     rule.id,
+			pluginIDTagName:   rule.pluginID,
+			repositoryTagName: rule.repository.Name,
+		}))
+	}
+
+	return collected, errors.Join(errs...)
+}
+
+// riskRule is the part of an accept or recast risk rule the collector looks at.
+type riskRule struct {
+	ruleType   string
+	id         string
+	pluginID   string
+	repository tenablesc.BaseInfo
+	hostType   string
+	hostValue  string
+	port       string
+	protocol   string
+	// expires is a unix time; -1, or anything not after the epoch, means the rule never expires.
+	expires string
+}
+
+func acceptRiskRule(rule *tenablesc.AcceptRiskRule) riskRule {
+	r := riskRule{
+		ruleType:  acceptRuleType,
+		id:        rule.ID,
+		hostType:  rule.HostType,
+		hostValue: rule.HostValue,
+		port:      rule.Port,
+		protocol:  rule.Protocol,
+		expires:   rule.Expires,
+	}
+	if rule.Plugin != nil {
+		r.pluginID = string(rule.Plugin.ID)
+	}
+	if rule.Repository != nil {
+		r.repository = *rule.Repository
+	}
+	return r
+}
+
+func recastRiskRule(rule *tenablesc.RecastRiskRule) riskRule {
+	return riskRule{
+		ruleType:   recastRuleType,
+		id:         rule.ID,
+		pluginID:   string(rule.Plugin.ID),
+		repository: rule.Repository,
+		hostType:   rule.HostType,
+		hostValue:  rule.HostValue,
+		port:       rule.Port,
+		protocol:   rule.Protocol,
+		expires:    rule.Expires,
+	}
+}
+
+// riskRuleHygiene counts the rules of each type, and those which never expire, expire within expiringWithin of
+// now, or have already expired.
+func riskRuleHygiene(rules []riskRule, now time.Time, expiringWithin time.Duration, org string) []metrics.Metric {
+	type counts struct {
+		total, withoutExpiry, expiringSoon, expired int64
+	}
+	byType := map[string]*counts{acceptRuleType: {}, recastRuleType: {}}
+
+	for _, rule := range rules {
+		c := byType[rule.ruleType]
+		c.total++
+
+		expires := epochStringToTime(rule.expires)
+		switch {
+		case expires.Unix() <= 0:
+			c.withoutExpiry++
+		case !expires.After(now):
+			c.expired++
+		case expires.Before(now.Add(expiringWithin)):
+			c.expiringSoon++
+		}
+	}
+
+	var collected []metrics.Metric
+	for ruleType, c := range byType {
+		tags := map[string]string{orgTagName: org, ruleTypeTagName: ruleType}
+		collected = append(collected,
+			metrics.NewMetric(riskRuleCountMetricName, metrics.Gauge, float64(c.total), tags),
+			metrics.NewMetric(riskRulesWithoutExpiryMetricName, metrics.Gauge, float64(c.withoutExpiry), tags),
+			metrics.NewMetric(riskRulesExpiringSoonMetricName, metrics.Gauge, float64(c.expiringSoon), tags),
+			metrics.NewMetric(riskRulesExpiredMetricName, metrics.Gauge, float64(c.expired), tags),
+		)
+	}
+	return collected
+}
+
+// riskRuleFindings counts the open findings the rule applies to. Accepted findings are hidden from analysis unless
+// asked for, so the query filters on the rule's accept or recast status as well as its plugin, repository and hosts.
+func (c *Client) riskRuleFindings(rule riskRule) (int64, error) {
+	filters, err := riskRuleFilters(rule)
+	if err != nil {
+		return 0, err
+	}
+
+	var total int64
+	err = analyzePages(c, "vulnipsummary", filters, defaultAnalysisPageSize, func(plugins []tenablesc.VulnIPSummaryResult) error {
+		for _, plugin := range plugins {
+			count, err := strconv.ParseInt(plugin.Total, 10, 64)
+			if err != nil {
+				return fmt.Errorf("failed to parse finding count of plugin %s: %w", plugin.PluginID, err)
+			}
+			total += count
+		}
+		return nil
+	})
+	return total, err
+}
+
+func riskRuleFilters(rule riskRule) ([]tenablesc.AnalysisFilter, error) {
+	filters := []tenablesc.AnalysisFilter{
+		{FilterName: "pluginID", Operator: "=", Value: rule.pluginID},
+	}
+	// Rules without a repository apply to all of them, and a filter on an empty ID would match none.
+	if rule.repository.ID != "" {
+		filters = append(filters, tenablesc.AnalysisFilter{FilterName: "repository", Operator: "=", Value: []tenablesc.BaseInfo{{ID: rule.repository.ID}}})
+	}
+	filters = append(filters, riskRuleStatusFilter(rule.ruleType))
+
+	switch rule.hostType {
+	case "all":
+	case "asset":
+		filters = append(filters, tenablesc.AnalysisFilter{FilterName: "asset", Operator: "=", Value: tenablesc.BaseInfo{ID: tenablesc.ProbablyString(rule.hostValue)}})
+	case "ip", "uuid":
+		filters = append(filters, tenablesc.AnalysisFilter{FilterName: rule.hostType, Operator: "=", Value: rule.hostValue})
+	default:
+		return nil, fmt.Errorf("unknown host type %q", rule.hostType)
+	}
+
+	if rule.port != "" && rule.port != "any" {
+		filters = append(filters, tenablesc.AnalysisFilter{FilterName: "port", Operator: "=", Value: rule.port})
+	}
+	if rule.protocol != "" && rule.protocol != "any" {
+		filters = append(filters, tenablesc.AnalysisFilter{FilterName: "protocol", Operator: "=", Value: rule.protocol})
+	}
+	return filters, nil
+}
+
+// riskRuleStatusFilter matches the findings rules of the type apply to, which analysis otherwise hides or shows
+// with their original severity.
+func riskRuleStatusFilter(ruleType string) tenablesc.AnalysisFilter {
+	if ruleType == acceptRuleType {
+		return tenablesc.AnalysisFilter{FilterName: "acceptRiskStatus", Operator: "=", Value: "accepted"}
+	}
+	return tenablesc.AnalysisFilter{FilterName: "recastRiskStatus", Operator: "=", Value: "recast"}
+}
@@ -0,0 +1,131 @@
+// Copyright 2022 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sc
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/palantir/tenablesc-client/tenablesc"
+)
+
+func Test_riskRuleHygiene(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	expiring := func(in time.Duration) string {
+		return strconv.FormatInt(now.Add(in).Unix(), 10)
+	}
+
+	rules := []riskRule{
+		{ruleType: acceptRuleType, expires: "-1"},
+		{ruleType: acceptRuleType, expires: expiring(-time.Hour)},
+		{ruleType: acceptRuleType, expires: expiring(10 * day)},
+		{ruleType: acceptRuleType, expires: expiring(60 * day)},
+		{ruleType: recastRuleType, expires: "0"},
+	}
+
+	got := make(map[string]float64)
+	for _, m := range riskRuleHygiene(rules, now, 30*day, "org") {
+		got[m.Key()] = m.Value
+	}
+	for key, want := range map[string]float64{
+		"riskRuleCount[org:org,ruleType:accept]":          4,
+		"riskRulesWithoutExpiry[org:org,ruleType:accept]": 1,
+		"riskRulesExpired[org:org,ruleType:accept]":       1,
+		"riskRulesExpiringSoon[org:org,ruleType:accept]":  1,
+		"riskRuleCount[org:org,ruleType:recast]":          1,
+		"riskRulesWithoutExpiry[org:org,ruleType:recast]": 1,
+		"riskRulesExpired[org:org,ruleType:recast]":       0,
+	} {
+		if value, ok := got[key]; !ok || value != want {
+			t.Errorf("%s = %v, want %v", key, value, want)
+		}
+	}
+}
+
+func Test_riskRuleFilters(t *testing.T) {
+	tests := []struct {
+		name        string
+		rule        riskRule
+		wantFilters []string
+		wantErr     bool
+	}{
+		{
+			name:        "repository",
+			rule:        riskRule{ruleType: acceptRuleType, pluginID: "19506", repository: tenablesc.BaseInfo{ID: "3"}, hostType: "all"},
+			wantFilters: []string{"pluginID", "repository", "acceptRiskStatus"},
+		},
+		{
+			name:        "all repositories",
+			rule:        riskRule{ruleType: recastRuleType, pluginID: "19506", hostType: "ip", hostValue: "10.0.0.1", port: "443", protocol: "any"},
+			wantFilters: []string{"pluginID", "recastRiskStatus", "ip", "port"},
+		},
+		{
+			name:    "unknown host type",
+			rule:    riskRule{ruleType: acceptRuleType, pluginID: "19506", hostType: "dnsName"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filters, err := riskRuleFilters(tt.rule)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("riskRuleFilters() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			var got []string
+			for _, filter := range filters {
+				got = append(got, filter.FilterName)
+			}
+			if !reflect.DeepEqual(got, tt.wantFilters) {
+				t.Errorf("riskRuleFilters() = %v, want %v", got, tt.wantFilters)
+			}
+		})
+	}
+}
+
+func Test_riskRulesCollector_Collect(t *testing.T) {
+	client := severityServer(t, map[string][]severitySummaryResult{
+		`acceptRiskStatus:"accepted"`: {severityRow("4", "2"), severityRow("2", "7")},
+		`recastRiskStatus:"recast"`:   {severityRow("0", "3")},
+	})
+
+	collected, err := (&riskRulesCollector{ExpiringWithin: 30 * day}).Collect(&Target{Client: client, Org: "foo"})
+	if err != nil {
+		t.Fatalf("Collect() error = %v", err)
+	}
+
+	got := make(map[string]float64)
+	for _, m := range collected {
+		if m.Name == suppressedFindingsMetricName {
+			got[m.Key()] = m.Value
+		}
+	}
+	want := map[string]float64{
+		"suppressedFindings[org:foo,ruleType:accept,severity:critical]": 2,
+		"suppressedFindings[org:foo,ruleType:accept,severity:high]":     0,
+		"suppressedFindings[org:foo,ruleType:accept,severity:medium]":   7,
+		"suppressedFindings[org:foo,ruleType:accept,severity:low]":      0,
+		"suppressedFindings[org:foo,ruleType:accept,severity:info]":     0,
+		"suppressedFindings[org:foo,ruleType:recast,severity:critical]": 0,
+		"suppressedFindings[org:foo,ruleType:recast,severity:high]":     0,
+		"suppressedFindings[org:foo,ruleType:recast,severity:medium]":   0,
+		"suppressedFindings[org:foo,ruleType:recast,severity:low]":      0,
+		"suppressedFindings[org:foo,ruleType:recast,severity:info]":     3,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("suppressed findings = %v, want %v", got, want)
+	}
+}
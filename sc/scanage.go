@@ -19,15 +19,62 @@ import (
 	"time"
 
 	"github.com/palantir/tenablesc-client/tenablesc"
+	"github.com/palantir/tenablesc-metrics/metrics"
 	"github.com/rs/zerolog/log"
 )
 
 const (
 	ceilingTimeInMinutes = 30 * 24 * 60
+
+	defaultNewScanGracePeriod = 48 * time.Hour
 )
 
-// getScheduledActiveScanAges returns a set of scan names and time since last scan in hours.
-func (c *Client) getScheduledActiveScanAges() (map[string]int64, error) {
+func init() {
+	RegisterCollector(scanAgeCollectorName, true, newScanAgeCollector)
+}
+
+type scanAgeCollector struct {
+	// MaxAge is reported for scans which should have results but have none.
+	MaxAge time.Duration `yaml:"maxAge"`
+	// NewScanGracePeriod is how long after creation a scan is exempt from reporting.
+	NewScanGracePeriod time.Duration `yaml:"newScanGracePeriod"`
+}
+
+func newScanAgeCollector(cfg CollectorConfig) (Collector, error) {
+	collector := &scanAgeCollector{
+		MaxAge:             ceilingTimeInMinutes * time.Minute,
+		NewScanGracePeriod: defaultNewScanGracePeriod,
+	}
+	if err := cfg.Decode(collector); err != nil {
+		return nil, err
+	}
+	return collector, nil
+}
+
+func (*scanAgeCollector) Name() string {
+	return scanAgeCollectorName
+}
+
+func (*scanAgeCollector) Scope() Scope {
+	return OrgScope
+}
+
+func (s *scanAgeCollector) Collect(t *Target) ([]metrics.Metric, error) {
+	var collected []metrics.Metric
+
+	scanAges, err := t.Client.getScheduledActiveScanAges(int64(s.MaxAge.Minutes()), s.NewScanGracePeriod)
+	if err != nil {
+		return nil, err
+	}
+	for scanName, metric := range scanAges {
+		collected = append(collected, metrics.NewMetric(minutesSinceLastScanMetricName, metrics.Gauge, float64(metric), map[string]string{orgTagName: t.Org, scanNameTagName: scanName}).WithUnit(minuteUnit))
+	}
+
+	return collected, nil
+}
+
+// getScheduledActiveScanAges returns a set of scan names and time since last scan in minutes.
+func (c *Client) getScheduledActiveScanAges(ceilingMinutes int64, newScanGracePeriod time.Duration) (map[string]int64, error) {
 
 	// round to hours? round to hours.
 	scanAges := make(map[string]int64)
@@ -37,7 +84,7 @@ func (c *Client) getScheduledActiveScanAges() (map[string]int64, error) {
 		return nil, err
 	}
 
-	scanResults, err := c.GetAllScanResults()
+	newestScanResults, err := c.newestScanResults()
 	if err != nil {
 		return nil, err
 	}
@@ -45,73 +92,42 @@ func (c *Client) getScheduledActiveScanAges() (map[string]int64, error) {
 	for _, scan := range scans {
 		log := log.With().Str("scan name", scan.Name).Logger()
 
-		if !shouldHaveScanResults(scan) {
+		if !shouldHaveScanResults(scan, newScanGracePeriod) {
 			log.Debug().Msg("scan not expected to have results")
 			continue
 		}
 
-		if newestScanResult := newestResultForScan(scan, scanResults); newestScanResult != nil {
-			scanAge := minutesSinceEpochString(string(newestScanResult.FinishTime))
+		if newestScanResult := newestScanResults[scan.Name]; newestScanResult != nil {
+			scanAge := minutesSinceEpochString(string(newestScanResult.FinishTime), ceilingMinutes)
 			log.Debug().Str("scanAgeEpoch", string(newestScanResult.FinishTime)).Int64("scanAgeDays", scanAge).Msg("got scan age")
 			scanAges[scan.Name] = scanAge
 		} else {
 			log.Debug().Msg("scan had no recent results")
-			if createdTime, err := scan.CreatedTime.ToDateTime(); err != nil {
-				// if it was created in the last 48h, don't report if no scan results.
-				if createdTime.After(time.Now().Add(-48 * time.Hour)) {
+			if createdTime, err := scan.CreatedTime.ToDateTime(); err == nil {
+				// if it was created in the grace period, don't report if no scan results.
+				if createdTime.After(time.Now().Add(-newScanGracePeriod)) {
 					log.Debug().Msg("scan is new, skipping report")
 					continue // if it's verifiably new and has no results, don't report it.
 				}
 			}
 			log.Debug().Msg("Giving scan max time due to no results.")
 			// Scan's been around, but has no scan results in the scan period, return max.
-			scanAges[scan.Name] = ceilingTimeInMinutes
+			scanAges[scan.Name] = ceilingMinutes
 		}
 	}
 
 	return scanAges, nil
 }
 
-func minutesSinceEpochString(s string) int64 {
+func minutesSinceEpochString(s string, ceilingMinutes int64) int64 {
 	t := epochStringToTime(s)
 	if t.IsZero() {
-		return ceilingTimeInMinutes
+		return ceilingMinutes
 	}
 
 	return int64(time.Since(t).Minutes())
 }
 
-func newestResultForScan(scan *tenablesc.Scan, results []*tenablesc.ScanResult) *tenablesc.ScanResult {
-	var newestResult *tenablesc.ScanResult
-	var newestResultTime time.Time
-	for _, result := range results {
-		if result.Name != scan.Name {
-			continue
-		}
-		if result.FinishTime == "-1" {
-			// scan not complete, move along
-			continue
-		}
-		if result.ImportStatus != "Finished" {
-			// scan is currently importing, so can't be considered finished.
-			// this can be an issue with job scheduling.
-			continue
-		}
-		if newestResult == nil {
-			newestResult = result
-			newestResultTime = epochStringToTime(string(result.FinishTime))
-		} else {
-			finishTime := epochStringToTime(string(result.FinishTime))
-			if finishTime.After(newestResultTime) {
-				newestResult = result
-				newestResultTime = finishTime
-			}
-		}
-	}
-
-	return newestResult
-}
-
 func epochStringToTime(s string) time.Time {
 	sec, err := strconv.ParseInt(s, 10, 64)
 	if err != nil {
@@ -122,12 +138,12 @@ func epochStringToTime(s string) time.Time {
 
 // We need to filter out scans which we don't care about the results of
 // which should include:
-// - scans created in the last 48h, which _might_ not have scan results yet.
+// - scans created within the grace period (48h by default), which _might_ not have scan results yet.
 // - scans whose schedule is ondemand or have no repeatrule
-func shouldHaveScanResults(scan *tenablesc.Scan) bool {
+func shouldHaveScanResults(scan *tenablesc.Scan, newScanGracePeriod time.Duration) bool {
 
 	createdTime := epochStringToTime(string(scan.CreatedTime))
-	if createdTime.After(time.Now().Add(-time.Hour * 48)) {
+	if createdTime.After(time.Now().Add(-newScanGracePeriod)) {
 		return false
 	}
 
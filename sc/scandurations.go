@@ -15,48 +15,103 @@
 package sc
 
 import (
+	"errors"
+	"fmt"
 	"strconv"
+	"time"
 
+	"github.com/palantir/tenablesc-metrics/metrics"
 	"github.com/rs/zerolog/log"
 )
 
+func init() {
+	RegisterCollector(scanDurationsCollectorName, true, newScanDurationsCollector)
+}
+
+type scanDurationsCollector struct {
+	// NewScanGracePeriod is how long after creation a scan is exempt from reporting.
+	NewScanGracePeriod time.Duration `yaml:"newScanGracePeriod"`
+}
+
+func newScanDurationsCollector(cfg CollectorConfig) (Collector, error) {
+	collector := &scanDurationsCollector{
+		NewScanGracePeriod: defaultNewScanGracePeriod,
+	}
+	if err := cfg.Decode(collector); err != nil {
+		return nil, err
+	}
+	return collector, nil
+}
+
+func (*scanDurationsCollector) Name() string {
+	return scanDurationsCollectorName
+}
+
+func (*scanDurationsCollector) Scope() Scope {
+	return OrgScope
+}
+
+func (s *scanDurationsCollector) Collect(t *Target) ([]metrics.Metric, error) {
+	var collected []metrics.Metric
+
+	scanDurations, err := t.Client.getScheduledActiveScanDurations(s.NewScanGracePeriod)
+	for scanName, duration := range scanDurations {
+		collected = append(collected, metrics.NewMetric(scanDurationSecondsMetricName, metrics.Distribution, float64(duration.seconds), map[string]string{orgTagName: t.Org, scanNameTagName: scanName}).
+			WithUnit(secondUnit).
+			WithTimestamp(duration.finishTime))
+	}
+
+	return collected, err
+}
+
+type scanDuration struct {
+	seconds    int64
+	finishTime time.Time
+}
+
 // getScheduledActiveScanDurations returns a set of scan names and how long the last one took to complete.
-func (c *Client) getScheduledActiveScanDurations() (map[string]int64, error) {
+// Scans whose duration can't be parsed are left out, and reported in the returned error.
+func (c *Client) getScheduledActiveScanDurations(newScanGracePeriod time.Duration) (map[string]scanDuration, error) {
 
-	// round to hours? round to hours.
-	scanDurations := make(map[string]int64)
+	scanDurations := make(map[string]scanDuration)
 
 	scans, err := c.GetAllScans()
 	if err != nil {
 		return nil, err
 	}
 
-	scanResults, err := c.GetAllScanResults()
+	newestScanResults, err := c.newestScanResults()
 	if err != nil {
 		return nil, err
 	}
 
+	var errs []error
 	for _, scan := range scans {
 		log := log.With().Str("scan name", scan.Name).Logger()
 
-		if !shouldHaveScanResults(scan) {
+		if !shouldHaveScanResults(scan, newScanGracePeriod) {
 			log.Debug().Msg("scan not expected to have results")
 			continue
 		}
 
-		if newestScanResult := newestResultForScan(scan, scanResults); newestScanResult != nil {
+		if newestScanResult := newestScanResults[scan.Name]; newestScanResult != nil {
 
-			scanDuration, err := strconv.ParseInt(string(newestScanResult.ScanDuration), 10, 64)
+			duration, err := strconv.ParseInt(string(newestScanResult.ScanDuration), 10, 64)
 			if err != nil {
-				return scanDurations, err
+				log.Err(err).Msg("Failed to parse scan duration, skipping.")
+				errs = append(errs, fmt.Errorf("failed to parse duration of scan %s: %w", scan.Name, err))
+				continue
 			}
 
-			log.Debug().Str(scanDurationSecondsMetricName, string(newestScanResult.ScanDuration)).Int64("scanAgeDays", scanDuration).Msg("got scan duration")
-			scanDurations[scan.Name] = scanDuration
+			log.Debug().Str(scanDurationSecondsMetricName, string(newestScanResult.ScanDuration)).Int64("scanDurationSeconds", duration).Msg("got scan duration")
+			scanDurations[scan.Name] = scanDuration{
+				seconds:    duration,
+				finishTime: epochStringToTime(string(newestScanResult.FinishTime)),
+			}
 		} else {
 			log.Debug().Msg("scan had no recent results")
 		}
 	}
 
-	return scanDurations, nil
+	return scanDurations, errors.Join(errs...)
 }
@@ -16,9 +16,45 @@ package sc
 
 import (
 	"github.com/palantir/tenablesc-client/tenablesc"
+	"github.com/palantir/tenablesc-metrics/metrics"
 	"github.com/rs/zerolog/log"
 )
 
+func init() {
+	RegisterCollector(scannerStatusCollectorName, true, newScannerStatusCollector)
+}
+
+type scannerStatusCollector struct{}
+
+func newScannerStatusCollector(cfg CollectorConfig) (Collector, error) {
+	return scannerStatusCollector{}, cfg.Decode(&struct{}{})
+}
+
+func (scannerStatusCollector) Name() string {
+	return scannerStatusCollectorName
+}
+
+func (scannerStatusCollector) Scope() Scope {
+	return AdminScope
+}
+
+func (scannerStatusCollector) Collect(t *Target) ([]metrics.Metric, error) {
+	scannerStatus, err := t.Client.getScannerStatus()
+	if err != nil {
+		return nil, err
+	}
+	return scannerStatusMetrics(scannerStatus), nil
+}
+
+func scannerStatusMetrics(scannerStatus scannerStatus) []metrics.Metric {
+	collected := scannerStatus.healthCount.metrics(nil)
+	for zone, status := range scannerStatus.ByZoneName {
+		collected = append(collected, status.metrics(map[string]string{scanZoneTagName: zone})...)
+	}
+
+	return collected
+}
+
 type scannerStatus struct {
 	healthCount
 
@@ -29,11 +65,15 @@ type healthCount struct {
 	Total, Healthy, Unhealthy int64
 }
 
-func (c *Client) getScannerStatus() (scannerStatus, error) {
-
-	status := scannerStatus{}
-	status.ByZoneName = make(map[string]*healthCount)
+func (h healthCount) metrics(tags map[string]string) []metrics.Metric {
+	return []metrics.Metric{
+		metrics.NewMetric(healthyScannerCountMetricName, metrics.Gauge, float64(h.Healthy), tags),
+		metrics.NewMetric(unhealthyScannerCountMetricName, metrics.Gauge, float64(h.Unhealthy), tags),
+		metrics.NewMetric(totalScannerCountMetricName, metrics.Gauge, float64(h.Total), tags),
+	}
+}
 
+func (c *Client) getScannerStatus() (scannerStatus, error) {
 	scanZones, err := c.GetAllScanZones()
 	if err != nil {
 		return scannerStatus{}, err
@@ -47,6 +87,14 @@ func (c *Client) getScannerStatus() (scannerStatus, error) {
 		return scannerStatus{}, err
 	}
 
+	return countScannerHealth(scanners, scannerIDToZoneNameMap), nil
+}
+
+// countScannerHealth counts healthy and unhealthy scanners, in total and by the name of their scan zone.
+func countScannerHealth(scanners []*tenablesc.Scanner, scannerIDToZoneNameMap map[string]string) scannerStatus {
+	status := scannerStatus{}
+	status.ByZoneName = make(map[string]*healthCount)
+
 	for _, scanner := range scanners {
 		zoneName := scannerIDToZoneNameMap[string(scanner.ID)]
 		if zoneName == "" {
@@ -71,7 +119,7 @@ func (c *Client) getScannerStatus() (scannerStatus, error) {
 		}
 	}
 
-	return status, nil
+	return status
 }
 
 func scannerIDToZoneNameMap(scanzones []*tenablesc.ScanZone) map[string]string {
@@ -0,0 +1,55 @@
+// Copyright 2022 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sc
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/palantir/tenablesc-client/tenablesc"
+)
+
+func Test_scannerStatusMetrics(t *testing.T) {
+	scanner := func(id, status string) *tenablesc.Scanner {
+		return &tenablesc.Scanner{BaseInfo: tenablesc.BaseInfo{ID: tenablesc.ProbablyString(id)}, Status: status}
+	}
+	scanners := []*tenablesc.Scanner{
+		scanner("1", "1"),
+		scanner("2", "1"),
+		scanner("3", "16384"),
+		scanner("4", "1"),
+	}
+	zones := map[string]string{"1": "dmz", "2": "dmz", "3": "dmz"}
+
+	got := make(map[string]float64)
+	for _, m := range scannerStatusMetrics(countScannerHealth(scanners, zones)) {
+		got[m.Key()] = m.Value
+	}
+
+	want := map[string]float64{
+		"healthyScannerCount":                                3,
+		"unhealthyScannerCount":                              1,
+		"totalScannerCount":                                  4,
+		"healthyScannerCount[scanZone:dmz]":                  2,
+		"unhealthyScannerCount[scanZone:dmz]":                1,
+		"totalScannerCount[scanZone:dmz]":                    3,
+		"healthyScannerCount[scanZone:no-associated-zone]":   1,
+		"unhealthyScannerCount[scanZone:no-associated-zone]": 0,
+		"totalScannerCount[scanZone:no-associated-zone]":     1,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("scannerStatusMetrics() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,323 @@
+// Copyright 2022 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sc
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/palantir/tenablesc-client/tenablesc"
+	"github.com/rs/zerolog/log"
+)
+
+// Pulling every scan result each cycle is by far the most expensive thing we do, and almost all of them are
+// unchanged since the last cycle. Instead each org keeps an index of the newest finished result per scan, plus a
+// watermark, and only asks SC for results newer than the watermark.
+
+const (
+	newestScanResultsEndpoint = "newestScanResults"
+
+	// scanResultWindow matches SC's default scanResult time window; indexed results older than this are forgotten
+	// so scans without a recent result are reported the same way as when fetching everything.
+	scanResultWindow = 30 * 24 * time.Hour
+	// watermarkOverlap re-fetches a little history every cycle to cover clock skew between us and SC.
+	watermarkOverlap = time.Hour
+)
+
+// scanResultState holds the scan result index of every org, and persists it to path if one is set.
+type scanResultState struct {
+	path string
+
+	mu   sync.Mutex
+	Orgs map[string]*orgScanResults `json:"orgs"`
+}
+
+// orgScanResults is the index for a single org.
+type orgScanResults struct {
+	state *scanResultState
+
+	// Watermark is the unix time the next fetch starts from; zero means fetch everything.
+	Watermark int64 `json:"watermark"`
+	// NewestResults maps scan name to its most recently finished, fully imported result.
+	NewestResults map[string]*tenablesc.ScanResult `json:"newestResults"`
+}
+
+func loadScanResultState(path string) (*scanResultState, error) {
+	state := &scanResultState{
+		path: path,
+		Orgs: make(map[string]*orgScanResults),
+	}
+	if path == "" {
+		return state, nil
+	}
+
+	bytes, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed reading state file %s: %w", path, err)
+	}
+
+	if err := json.Unmarshal(bytes, state); err != nil {
+		// The index can always be rebuilt from SC, so a bad file only costs us one full fetch.
+		log.Warn().Err(err).Str("stateFile", path).Msg("discarding unreadable state file")
+		state.Orgs = make(map[string]*orgScanResults)
+	}
+	if state.Orgs == nil {
+		state.Orgs = make(map[string]*orgScanResults)
+	}
+
+	return state, nil
+}
+
+// forOrg returns the index for the named org, creating an empty one if needed.
+func (s *scanResultState) forOrg(org string) *orgScanResults {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	index, exists := s.Orgs[org]
+	if !exists {
+		index = &orgScanResults{}
+		s.Orgs[org] = index
+	}
+	if index.NewestResults == nil {
+		index.NewestResults = make(map[string]*tenablesc.ScanResult)
+	}
+	index.state = s
+
+	return index
+}
+
+// saveLocked atomically replaces the state file; callers must hold s.mu.
+func (s *scanResultState) saveLocked() error {
+	if s.path == "" {
+		return nil
+	}
+
+	bytes, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	if _, err := tmp.Write(bytes); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), s.path)
+}
+
+// update fetches results newer than the watermark, folds them into the index and advances the watermark.
+// It returns a copy of the index, so callers can read it without holding the lock.
+func (o *orgScanResults) update(fetchSince func(start time.Time) ([]*tenablesc.ScanResult, error)) (map[string]*tenablesc.ScanResult, error) {
+	o.state.mu.Lock()
+	var since time.Time
+	if o.Watermark > 0 {
+		since = time.Unix(o.Watermark, 0)
+	}
+	o.state.mu.Unlock()
+
+	fetchStart := time.Now()
+	log.Debug().Time("since", since).Msg("fetching scan results")
+	results, err := fetchSince(since)
+	if err != nil {
+		return nil, err
+	}
+
+	o.state.mu.Lock()
+	defer o.state.mu.Unlock()
+
+	mergeNewestResults(o.NewestResults, results)
+	pruneResultsFinishedBefore(o.NewestResults, fetchStart.Add(-scanResultWindow))
+	o.Watermark = nextWatermark(results, fetchStart).Unix()
+
+	if err := o.state.saveLocked(); err != nil {
+		log.Warn().Err(err).Str("stateFile", o.state.path).Msg("failed to persist scan result state")
+	}
+
+	newest := make(map[string]*tenablesc.ScanResult, len(o.NewestResults))
+	for name, result := range o.NewestResults {
+		newest[name] = result
+	}
+	return newest, nil
+}
+
+// newestScanResults returns the most recently finished, fully imported result for each scan name.
+func (c *Client) newestScanResults() (map[string]*tenablesc.ScanResult, error) {
+	return cachedFetch(c, newestScanResultsEndpoint, func() (map[string]*tenablesc.ScanResult, error) {
+		if c.scanResults != nil {
+			return c.scanResults.update(func(start time.Time) ([]*tenablesc.ScanResult, error) {
+				// the client sends a broken endTime when one is set, so leave it open ended.
+				results, err := c.Client.GetAllScanResultsByTime(start, tenablesc.DefaultTimeScope)
+				if err == nil {
+					c.recordScanResults(results)
+				}
+				return results, err
+			})
+		}
+
+		results, err := c.GetAllScanResults()
+		if err != nil {
+			return nil, err
+		}
+		newest := make(map[string]*tenablesc.ScanResult)
+		mergeNewestResults(newest, results)
+		return newest, nil
+	})
+}
+
+// mergeNewestResults folds results into newest, keeping the latest finished result for each scan name.
+func mergeNewestResults(newest map[string]*tenablesc.ScanResult, results []*tenablesc.ScanResult) {
+	for _, result := range results {
+		if !resultIsFinished(result) {
+			continue
+		}
+
+		current, exists := newest[result.Name]
+		if !exists || epochStringToTime(string(result.FinishTime)).After(epochStringToTime(string(current.FinishTime))) {
+			newest[result.Name] = trimScanResult(result)
+		}
+	}
+}
+
+func pruneResultsFinishedBefore(newest map[string]*tenablesc.ScanResult, cutoff time.Time) {
+	for name, result := range newest {
+		if epochStringToTime(string(result.FinishTime)).Before(cutoff) {
+			delete(newest, name)
+		}
+	}
+}
+
+// nextWatermark picks where the next fetch should start: far enough back to see every result which was still
+// running or importing in this fetch finish, and never later than this fetch minus the overlap.
+func nextWatermark(results []*tenablesc.ScanResult, fetchStart time.Time) time.Time {
+	watermark := fetchStart.Add(-watermarkOverlap)
+	for _, result := range results {
+		if resultIsFinished(result) {
+			continue
+		}
+		startTime := epochStringToTime(string(result.StartTime))
+		if startTime.Unix() > 0 && startTime.Before(watermark) {
+			watermark = startTime
+		}
+	}
+	return watermark
+}
+
+func resultIsFinished(result *tenablesc.ScanResult) bool {
+	if result.FinishTime == "-1" {
+		// scan not complete, move along
+		return false
+	}
+	if result.ImportStatus != "Finished" {
+		// scan is currently importing, so can't be considered finished.
+		// this can be an issue with job scheduling.
+		return false
+	}
+	return true
+}
+
+// trimScanResult keeps only the fields collectors use, to keep the state file small.
+func trimScanResult(result *tenablesc.ScanResult) *tenablesc.ScanResult {
+	return &tenablesc.ScanResult{
+		BaseInfo: tenablesc.BaseInfo{
+			ID:   result.ID,
+			Name: result.Name,
+		},
+		ImportStatus: result.ImportStatus,
+		StartTime:    result.StartTime,
+		FinishTime:   result.FinishTime,
+		ScanDuration: result.ScanDuration,
+	}
+}
+
+// ScanResultFact is a scan result as SC reported it, unfinished or not, for recording outside the index.
+type ScanResultFact struct {
+	Org          string
+	ID           string
+	ScanName     string
+	Status       string
+	ImportStatus string
+	// StartTime and FinishTime are zero while SC has no value for them.
+	StartTime  time.Time
+	FinishTime time.Time
+	// ScanDuration is in seconds.
+	ScanDuration int64
+	TotalIPs     int64
+	ScannedIPs   int64
+}
+
+// ScanResultRecorder receives the scan results fetched for each org. The same result is seen again every cycle
+// until it falls behind the watermark, so recorders should key on Org and ID.
+type ScanResultRecorder interface {
+	RecordScanResults(facts []ScanResultFact) error
+}
+
+// recordScanResults passes results on to the client's recorder. Failing to record is logged, but doesn't fail
+// the collection the results were fetched for.
+func (c *Client) recordScanResults(results []*tenablesc.ScanResult) {
+	if c.recorder == nil || len(results) == 0 {
+		return
+	}
+
+	facts := make([]ScanResultFact, 0, len(results))
+	for _, result := range results {
+		facts = append(facts, ScanResultFact{
+			Org:          c.org,
+			ID:           string(result.ID),
+			ScanName:     result.Name,
+			Status:       result.Status,
+			ImportStatus: result.ImportStatus,
+			StartTime:    factTime(string(result.StartTime)),
+			FinishTime:   factTime(string(result.FinishTime)),
+			ScanDuration: factInt(string(result.ScanDuration)),
+			TotalIPs:     factInt(string(result.TotalIPs)),
+			ScannedIPs:   factInt(string(result.ScannedIPs)),
+		})
+	}
+
+	if err := c.recorder.RecordScanResults(facts); err != nil {
+		log.Warn().Err(err).Str("org", c.org).Msg("failed to record scan results")
+	}
+}
+
+// factTime is epochStringToTime, except that SC's placeholders for "not yet" come out as the zero time.
+func factTime(s string) time.Time {
+	t := epochStringToTime(s)
+	if t.Unix() <= 0 {
+		return time.Time{}
+	}
+	return t
+}
+
+func factInt(s string) int64 {
+	i, _ := strconv.ParseInt(s, 10, 64)
+	return i
+}
@@ -0,0 +1,116 @@
+// Copyright 2022 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sc
+
+import (
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/palantir/tenablesc-client/tenablesc"
+)
+
+func epochString(t time.Time) tenablesc.ProbablyString {
+	return tenablesc.ProbablyString(strconv.FormatInt(t.Unix(), 10))
+}
+
+func Test_orgScanResults_update(t *testing.T) {
+	now := time.Now()
+	statePath := filepath.Join(t.TempDir(), "state.json")
+
+	state, err := loadScanResultState(statePath)
+	if err != nil {
+		t.Fatalf("loadScanResultState() error = %v", err)
+	}
+
+	running := &tenablesc.ScanResult{
+		BaseInfo:     tenablesc.BaseInfo{ID: "3", Name: "weekly"},
+		StartTime:    epochString(now.Add(-5 * time.Hour)),
+		FinishTime:   "-1",
+		ImportStatus: "No Results",
+	}
+	firstFetch := []*tenablesc.ScanResult{
+		{
+			BaseInfo:     tenablesc.BaseInfo{ID: "1", Name: "daily"},
+			StartTime:    epochString(now.Add(-26 * time.Hour)),
+			FinishTime:   epochString(now.Add(-25 * time.Hour)),
+			ScanDuration: "3600",
+			ImportStatus: "Finished",
+		},
+		{
+			BaseInfo:     tenablesc.BaseInfo{ID: "2", Name: "daily"},
+			StartTime:    epochString(now.Add(-2 * time.Hour)),
+			FinishTime:   epochString(now.Add(-1 * time.Hour)),
+			ScanDuration: "3500",
+			ImportStatus: "Finished",
+		},
+		{
+			BaseInfo:     tenablesc.BaseInfo{ID: "0", Name: "ancient"},
+			FinishTime:   epochString(now.Add(-60 * 24 * time.Hour)),
+			ImportStatus: "Finished",
+		},
+		running,
+	}
+
+	var gotSince time.Time
+	newest, err := state.forOrg("org").update(func(start time.Time) ([]*tenablesc.ScanResult, error) {
+		gotSince = start
+		return firstFetch, nil
+	})
+	if err != nil {
+		t.Fatalf("update() error = %v", err)
+	}
+	if !gotSince.IsZero() {
+		t.Errorf("first fetch started from %v, want everything", gotSince)
+	}
+	if got := newest["daily"]; got == nil || got.ID != "2" {
+		t.Errorf("newest daily result = %v, want id 2", got)
+	}
+	if _, exists := newest["weekly"]; exists {
+		t.Errorf("unfinished result should not be indexed")
+	}
+	if _, exists := newest["ancient"]; exists {
+		t.Errorf("results outside the window should be pruned")
+	}
+
+	// Reload from disk, as a new process would.
+	state, err = loadScanResultState(statePath)
+	if err != nil {
+		t.Fatalf("loadScanResultState() error = %v", err)
+	}
+
+	finished := *running
+	finished.FinishTime = epochString(now)
+	finished.ScanDuration = "18000"
+	finished.ImportStatus = "Finished"
+
+	newest, err = state.forOrg("org").update(func(start time.Time) ([]*tenablesc.ScanResult, error) {
+		gotSince = start
+		return []*tenablesc.ScanResult{&finished}, nil
+	})
+	if err != nil {
+		t.Fatalf("update() error = %v", err)
+	}
+	if want := epochStringToTime(string(running.StartTime)); !gotSince.Equal(want) {
+		t.Errorf("second fetch started from %v, want the running result's start %v", gotSince, want)
+	}
+	if got := newest["daily"]; got == nil || got.ID != "2" {
+		t.Errorf("newest daily result = %v, want id 2 carried over from the state file", got)
+	}
+	if got := newest["weekly"]; got == nil || got.ScanDuration != "18000" {
+		t.Errorf("newest weekly result = %v, want the now finished result", got)
+	}
+}
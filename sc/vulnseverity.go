@@ -0,0 +1,193 @@
+// Copyright 2022 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sc
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strconv"
+
+	"github.com/palantir/tenablesc-client/tenablesc"
+	"github.com/palantir/tenablesc-metrics/metrics"
+	"github.com/rs/zerolog/log"
+)
+
+// severities are the severities counts are reported for, from SC's severity id 4 down to 0.
+var severities = []string{"critical", "high", "medium", "low", "info"}
+
+func init() {
+	// Disabled by default: every repository and asset list costs another analysis query.
+	RegisterCollector(vulnSeverityCollectorName, false, newVulnSeverityCollector)
+}
+
+type vulnSeverityCollector struct {
+	// Assets are glob patterns for the asset lists counts are also reported for; by default none.
+	Assets []string `yaml:"assets"`
+}
+
+func newVulnSeverityCollector(cfg CollectorConfig) (Collector, error) {
+	collector := &vulnSeverityCollector{}
+	if err := cfg.Decode(collector); err != nil {
+		return nil, err
+	}
+	for _, pattern := range collector.Assets {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("bad asset pattern %q: %w", pattern, err)
+		}
+	}
+	return collector, nil
+}
+
+func (*vulnSeverityCollector) Name() string {
+	return vulnSeverityCollectorName
+}
+
+func (*vulnSeverityCollector) Scope() Scope {
+	return OrgScope
+}
+
+// Collect counts open vulnerabilities by severity, per repository and per matching asset list, with one
+// severity summary query for each.
+func (v *vulnSeverityCollector) Collect(t *Target) ([]metrics.Metric, error) {
+	repositories, err := t.Client.GetAllRepositories()
+	if err != nil {
+		return nil, err
+	}
+
+	var collected []metrics.Metric
+	var errs []error
+	for _, repository := range repositories {
+		filter := tenablesc.AnalysisFilter{FilterName: "repository", Operator: "=", Value: []tenablesc.BaseInfo{{ID: repository.ID}}}
+		counts, err := t.Client.severitySummary([]tenablesc.AnalysisFilter{filter})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed counting vulnerabilities of repository %s: %w", repository.Name, err))
+			continue
+		}
+		collected = append(collected, counts.metrics(vulnerabilityCountMetricName, map[string]string{orgTagName: t.Org, repositoryTagName: repository.Name})...)
+	}
+
+	if len(v.Assets) == 0 {
+		return collected, errors.Join(errs...)
+	}
+	assets, err := t.Client.GetAllAssets()
+	if err != nil {
+		return collected, errors.Join(append(errs, err)...)
+	}
+	for _, asset := range assets {
+		if !matchesAnyPattern(v.Assets, asset.Name) {
+			continue
+		}
+
+		filter := tenablesc.AnalysisFilter{FilterName: "asset", Operator: "=", Value: tenablesc.BaseInfo{ID: asset.ID}}
+		counts, err := t.Client.severitySummary([]tenablesc.AnalysisFilter{filter})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed counting vulnerabilities of asset %s: %w", asset.Name, err))
+			continue
+		}
+		collected = append(collected, counts.metrics(assetVulnerabilityCountMetricName, map[string]string{orgTagName: t.Org, assetNameTagName: asset.Name})...)
+	}
+
+	return collected, errors.Join(errs...)
+}
+
+// severityCounts holds a count for each of severities, in the same order.
+type severityCounts [5]int64
+
+func (s *severityCounts) add(other severityCounts) {
+	for i := range s {
+		s[i] += other[i]
+	}
+}
+
+func (s severityCounts) metrics(name string, tags map[string]string) []metrics.Metric {
+	var collected []metrics.Metric
+	for i, severity := range severities {
+		severityTags := map[string]string{severityTagName: severity}
+		for k, v := range tags {
+			severityTags[k] = v
+		}
+		collected = append(collected, metrics.NewMetric(name, metrics.Gauge, float64(s[i]), severityTags))
+	}
+	return collected
+}
+
+// severitySummaryResult is a row of the sumseverity analysis tool, which the SC client doesn't support.
+type severitySummaryResult struct {
+	Severity tenablesc.BaseInfo `json:"severity"`
+	Count    string             `json:"count"`
+}
+
+// severitySummary counts the open vulnerabilities matching the filters by severity, in a single query.
+func (c *Client) severitySummary(filters []tenablesc.AnalysisFilter) (severityCounts, error) {
+	analysis := &tenablesc.Analysis{
+		Type:       "vuln",
+		SourceType: "cumulative",
+		Query: tenablesc.AnalysisQuery{
+			Type:       "vuln",
+			SourceType: "cumulative",
+			Tool:       "sumseverity",
+			Filters:    filters,
+		},
+		Columns:     []tenablesc.BaseInfo{},
+		StartOffset: "0",
+		EndOffset:   strconv.Itoa(len(severities)),
+	}
+
+	var rows []severitySummaryResult
+	if _, err := c.analyze(analysis, &rows); err != nil {
+		return severityCounts{}, err
+	}
+	return parseSeveritySummary(rows), nil
+}
+
+// parseSeveritySummary sorts the rows into severityCounts. A row which can't be read is logged and left out
+// rather than failing the count, as the other severities are still right.
+func parseSeveritySummary(rows []severitySummaryResult) severityCounts {
+	var counts severityCounts
+	for _, row := range rows {
+		severity, ok := severityIndex(string(row.Severity.ID))
+		if !ok {
+			log.Warn().Str("severity", string(row.Severity.ID)).Msg("ignoring vulnerability count of unknown severity")
+			continue
+		}
+		count, err := strconv.ParseInt(row.Count, 10, 64)
+		if err != nil {
+			log.Warn().Err(err).Str("severity", severities[severity]).Msg("ignoring unreadable vulnerability count")
+			continue
+		}
+		counts[severity] = count
+	}
+	return counts
+}
+
+// severityIndex maps SC's severity IDs, 0 for info up to 4 for critical, to indexes of severities.
+func severityIndex(id string) (int, bool) {
+	severity, err := strconv.Atoi(id)
+	if err != nil || severity < 0 || severity >= len(severities) {
+		return 0, false
+	}
+	return len(severities) - 1 - severity, true
+}
+
+func matchesAnyPattern(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		// patterns were checked when the collector was built.
+		if matched, _ := path.Match(pattern, name); matched {
+			return true
+		}
+	}
+	return false
+}
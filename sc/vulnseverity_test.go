@@ -0,0 +1,127 @@
+// Copyright 2022 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sc
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strconv"
+	"testing"
+
+	"github.com/palantir/tenablesc-client/tenablesc"
+)
+
+// severityServer answers the repository and asset lists, empty risk rule lists, and sumseverity queries with the
+// rows given for the filter value of each query.
+func severityServer(t *testing.T, rows map[string][]severitySummaryResult) *Client {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var response interface{}
+		switch r.URL.Path {
+		case "/repository":
+			response = []map[string]string{{"id": "1", "name": "prod"}, {"id": "2", "name": "dev"}}
+		case "/asset":
+			response = map[string]interface{}{
+				"usable":     []map[string]string{{"id": "10", "name": "prod-web"}, {"id": "11", "name": "laptops"}},
+				"manageable": []map[string]string{},
+			}
+		case "/acceptRiskRule", "/recastRiskRule":
+			response = []interface{}{}
+		case "/analysis":
+			var analysis tenablesc.Analysis
+			if err := json.NewDecoder(r.Body).Decode(&analysis); err != nil || analysis.Query.Tool != "sumseverity" || len(analysis.Query.Filters) != 1 {
+				t.Errorf("unexpected analysis request %+v: %v", analysis, err)
+			}
+			filter := analysis.Query.Filters[0]
+			value, _ := json.Marshal(filter.Value)
+			results, _ := json.Marshal(rows[filter.FilterName+":"+string(value)])
+			response = tenablesc.AnalysisResponseContainer{Results: results}
+		default:
+			http.NotFound(w, r)
+			return
+		}
+		encoded, _ := json.Marshal(response)
+		_ = json.NewEncoder(w).Encode(tenablesc.SCResponse{Response: encoded})
+	}))
+	t.Cleanup(server.Close)
+	return &Client{Client: *tenablesc.NewClient(server.URL), cache: newResponseCache(), url: server.URL, httpClient: server.Client()}
+}
+
+func severityRow(id, count string) severitySummaryResult {
+	return severitySummaryResult{Severity: tenablesc.BaseInfo{ID: tenablesc.ProbablyString(id)}, Count: count}
+}
+
+func Test_vulnSeverityCollector_Collect(t *testing.T) {
+	client := severityServer(t, map[string][]severitySummaryResult{
+		`repository:[{"id":"1"}]`: {severityRow("4", "2"), severityRow("3", "5"), severityRow("0", "40")},
+		// rows which can't be read are left out, and the rest still counted.
+		`repository:[{"id":"2"}]`: {severityRow("2", "3"), severityRow("1", "n/a"), severityRow("9", "1")},
+		`asset:{"id":"10"}`:       {severityRow("4", "1")},
+	})
+
+	tests := []struct {
+		name   string
+		assets []string
+		want   []string
+	}{
+		{
+			name: "repositories by default",
+			want: []string{
+				"vulnerabilityCount[org:foo,repository:dev,severity:critical]=0",
+				"vulnerabilityCount[org:foo,repository:dev,severity:high]=0",
+				"vulnerabilityCount[org:foo,repository:dev,severity:info]=0",
+				"vulnerabilityCount[org:foo,repository:dev,severity:low]=0",
+				"vulnerabilityCount[org:foo,repository:dev,severity:medium]=3",
+				"vulnerabilityCount[org:foo,repository:prod,severity:critical]=2",
+				"vulnerabilityCount[org:foo,repository:prod,severity:high]=5",
+				"vulnerabilityCount[org:foo,repository:prod,severity:info]=40",
+				"vulnerabilityCount[org:foo,repository:prod,severity:low]=0",
+				"vulnerabilityCount[org:foo,repository:prod,severity:medium]=0",
+			},
+		},
+		{
+			name:   "matching assets",
+			assets: []string{"prod-*"},
+			want: []string{
+				"assetVulnerabilityCount[assetName:prod-web,org:foo,severity:critical]=1",
+				"assetVulnerabilityCount[assetName:prod-web,org:foo,severity:high]=0",
+				"assetVulnerabilityCount[assetName:prod-web,org:foo,severity:info]=0",
+				"assetVulnerabilityCount[assetName:prod-web,org:foo,severity:low]=0",
+				"assetVulnerabilityCount[assetName:prod-web,org:foo,severity:medium]=0",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			collected, err := (&vulnSeverityCollector{Assets: tt.assets}).Collect(&Target{Client: client, Org: "foo"})
+			if err != nil {
+				t.Fatalf("Collect() error = %v", err)
+			}
+
+			var got []string
+			for _, m := range collected {
+				if tt.assets == nil || m.Name == assetVulnerabilityCountMetricName {
+					got = append(got, m.Key()+"="+strconv.FormatFloat(m.Value, 'f', -1, 64))
+				}
+			}
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Collect() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
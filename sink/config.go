@@ -0,0 +1,188 @@
+// Copyright 2022 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sink
+
+import (
+	"fmt"
+	"path"
+	"sort"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Factory builds a Sink from its configuration entry.
+type Factory func(c Config) (Sink, error)
+
+var sinkTypes = make(map[string]Factory)
+
+// registerType makes a sink type available in the sinks list; it panics if the type is already taken.
+func registerType(name string, factory Factory) {
+	if _, exists := sinkTypes[name]; exists {
+		panic(fmt.Sprintf("sink type %s registered twice", name))
+	}
+	sinkTypes[name] = factory
+}
+
+// Types returns the sorted names of every sink type.
+func Types() []string {
+	var names []string
+	for name := range sinkTypes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Config is a single entry of the sinks list.
+// Apart from the keys below, every key is specific to the sink type and is decoded by its factory.
+type Config struct {
+	Type string
+	// Name tells apart several sinks of the same type in logs and metrics; it defaults to the type.
+	Name string
+	// Include and Exclude are glob patterns matched against metric names; a metric is sent if it matches any
+	// include pattern (or there are none) and no exclude pattern.
+	Include []string
+	Exclude []string
+	// RewriteTags is applied to the metrics which pass the filters.
+	RewriteTags TagRewrite
+
+	options map[string]interface{}
+}
+
+// TagRewrite changes the tags a sink sees: renames apply first, then drops, then additions.
+type TagRewrite struct {
+	Rename map[string]string `yaml:"rename"`
+	Drop   []string          `yaml:"drop"`
+	Add    map[string]string `yaml:"add"`
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler
+func (c *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var common struct {
+		Type        string     `yaml:"type"`
+		Name        string     `yaml:"name"`
+		Include     []string   `yaml:"include"`
+		Exclude     []string   `yaml:"exclude"`
+		RewriteTags TagRewrite `yaml:"rewriteTags"`
+	}
+	var raw map[string]interface{}
+	if err := unmarshal(&raw); err != nil {
+		return err
+	}
+
+	// The common keys are split out first, as strict unmarshalling would reject the type specific ones.
+	known := make(map[string]interface{})
+	for _, key := range []string{"type", "name", "include", "exclude", "rewriteTags"} {
+		if value, ok := raw[key]; ok {
+			known[key] = value
+			delete(raw, key)
+		}
+	}
+	bytes, err := yaml.Marshal(known)
+	if err != nil {
+		return err
+	}
+	if err := yaml.UnmarshalStrict(bytes, &common); err != nil {
+		return err
+	}
+
+	*c = Config{
+		Type:        common.Type,
+		Name:        common.Name,
+		Include:     common.Include,
+		Exclude:     common.Exclude,
+		RewriteTags: common.RewriteTags,
+		options:     raw,
+	}
+	return nil
+}
+
+// Decode strictly unmarshals the type specific options into dest.
+// Fields of dest which are not present in the config are left untouched, so defaults can be set beforehand.
+func (c Config) Decode(dest interface{}) error {
+	if len(c.options) == 0 {
+		return nil
+	}
+
+	bytes, err := yaml.Marshal(c.options)
+	if err != nil {
+		return err
+	}
+
+	return yaml.UnmarshalStrict(bytes, dest)
+}
+
+// SetDefault sets a type specific option unless the config already has it.
+func (c *Config) SetDefault(key string, value interface{}) {
+	if _, ok := c.options[key]; ok {
+		return
+	}
+	if c.options == nil {
+		c.options = make(map[string]interface{})
+	}
+	c.options[key] = value
+}
+
+func (c Config) name() string {
+	if c.Name != "" {
+		return c.Name
+	}
+	return c.Type
+}
+
+// ValidateConfigs checks the parts of the sinks list which don't need the sinks to be built.
+func ValidateConfigs(configs []Config) error {
+	names := make(map[string]bool)
+	for _, c := range configs {
+		if _, exists := sinkTypes[c.Type]; !exists {
+			return fmt.Errorf("unknown sink type %q, must be one of %v", c.Type, Types())
+		}
+		if names[c.name()] {
+			return fmt.Errorf("sink name %s used twice; set name to tell them apart", c.name())
+		}
+		names[c.name()] = true
+
+		for _, pattern := range append(append([]string(nil), c.Include...), c.Exclude...) {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("invalid pattern %q for sink %s: %w", pattern, c.name(), err)
+			}
+		}
+	}
+	return nil
+}
+
+// NewAll builds every sink in the list, closing any already built if one fails.
+func NewAll(configs []Config) ([]Sink, error) {
+	if err := ValidateConfigs(configs); err != nil {
+		return nil, err
+	}
+
+	var sinks []Sink
+	for _, c := range configs {
+		s, err := sinkTypes[c.Type](c)
+		if err != nil {
+			CloseAll(sinks)
+			return nil, fmt.Errorf("failed to configure sink %s: %w", c.name(), err)
+		}
+		sinks = append(sinks, &configured{
+			Sink:    s,
+			name:    c.name(),
+			include: c.Include,
+			exclude: c.Exclude,
+			rewrite: c.RewriteTags,
+		})
+	}
+	return sinks, nil
+}
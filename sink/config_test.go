@@ -0,0 +1,114 @@
+// Copyright 2022 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sink
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/palantir/tenablesc-metrics/metrics"
+	"gopkg.in/yaml.v2"
+)
+
+// recorder keeps the snapshots written to it, failing every write if fail is set.
+type recorder struct {
+	Fail    bool `yaml:"fail"`
+	written []*metrics.Snapshot
+}
+
+func (r *recorder) Name() string { return "recorder" }
+
+func (r *recorder) Write(_ context.Context, snapshot *metrics.Snapshot) error {
+	if r.Fail {
+		return errors.New("recorder failed")
+	}
+	r.written = append(r.written, snapshot)
+	return nil
+}
+
+func (r *recorder) Close() error { return nil }
+
+var recorders = make(map[string]*recorder)
+
+func init() {
+	registerType("recorder", func(c Config) (Sink, error) {
+		r := &recorder{}
+		if err := c.Decode(r); err != nil {
+			return nil, err
+		}
+		recorders[c.name()] = r
+		return r, nil
+	})
+}
+
+func TestNewAll(t *testing.T) {
+	var configs []Config
+	err := yaml.UnmarshalStrict([]byte(`
+- type: recorder
+  name: scans
+  include: ["scan*", "minutesSinceLastScan"]
+  exclude: ["scanCount"]
+  rewriteTags:
+    rename: {scanName: scan}
+    drop: [org]
+    add: {env: test}
+- type: recorder
+  name: broken
+  fail: true
+- type: recorder
+`), &configs)
+	if err != nil {
+		t.Fatalf("failed to parse sinks: %v", err)
+	}
+
+	sinks, err := NewAll(configs)
+	if err != nil {
+		t.Fatalf("NewAll() error = %v", err)
+	}
+
+	snapshot := metrics.NewSnapshot(time.Now(), []metrics.Metric{
+		metrics.NewMetric("minutesSinceLastScan", metrics.Gauge, 10, map[string]string{metrics.OrgTagName: "foo", "scanName": "daily"}),
+		metrics.NewMetric("scanCount", metrics.Gauge, 4, nil),
+		metrics.NewMetric("failedUpdate", metrics.Gauge, 0, nil),
+	}, nil)
+
+	results := WriteAll(context.Background(), sinks, snapshot)
+	if results[0].Err != nil || results[1].Err == nil || results[2].Err != nil {
+		t.Errorf("WriteAll() = %+v, want only broken to fail", results)
+	}
+	if results[1].Sink != "broken" || results[2].Sink != "recorder" {
+		t.Errorf("WriteAll() = %+v, want results named after their sinks", results)
+	}
+
+	filtered := recorders["scans"].written[0].Metrics()
+	if len(filtered) != 1 || filtered[0].Key() != "minutesSinceLastScan[env:test,scan:daily]" {
+		t.Errorf("filtered sink got %v", filtered)
+	}
+	if got := recorders["recorder"].written[0].Len(); got != 3 {
+		t.Errorf("unfiltered sink got %d metrics, want 3", got)
+	}
+
+	for _, bad := range []string{"[{type: nope}]", "[{type: recorder}, {type: recorder}]", "[{type: recorder, include: ['[']}]"} {
+		var configs []Config
+		if err := yaml.UnmarshalStrict([]byte(bad), &configs); err != nil {
+			t.Fatalf("failed to parse %s: %v", bad, err)
+		}
+		if err := ValidateConfigs(configs); err == nil {
+			t.Errorf("ValidateConfigs(%s) succeeded", bad)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+// Copyright 2022 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sink
+
+import (
+	"context"
+
+	"github.com/DataDog/datadog-go/v5/statsd"
+	"github.com/palantir/go-baseapp/baseapp/datadog"
+	"github.com/palantir/tenablesc-metrics/metrics"
+)
+
+// DefaultDatadogAddress is the statsd agent the datadog sink sends to unless configured otherwise.
+const DefaultDatadogAddress = "localhost:8125"
+
+func init() {
+	registerType("datadog", func(c Config) (Sink, error) {
+		options := struct {
+			Address string   `yaml:"address"`
+			Tags    []string `yaml:"tags"`
+		}{Address: DefaultDatadogAddress}
+		if err := c.Decode(&options); err != nil {
+			return nil, err
+		}
+		return NewDatadog(options.Address, options.Tags)
+	})
+}
+
+// Datadog sends snapshots to a statsd agent through the go-baseapp emitter.
+type Datadog struct {
+	client *statsd.Client
+}
+
+// NewDatadog connects to the statsd agent at address, adding tags to every metric.
+func NewDatadog(address string, tags []string) (*Datadog, error) {
+	client, err := statsd.New(address, statsd.WithTags(tags))
+	if err != nil {
+		return nil, err
+	}
+	return &Datadog{client: client}, nil
+}
+
+func (d *Datadog) Name() string {
+	return "datadog"
+}
+
+func (d *Datadog) Write(_ context.Context, snapshot *metrics.Snapshot) error {
+	// A fresh registry per cycle, so only this cycle's series are emitted.
+	emitter := datadog.NewEmitter(d.client, metrics.NewRegistry(snapshot.Metrics()))
+	emitter.EmitOnce()
+	return emitter.Flush()
+}
+
+func (d *Datadog) Close() error {
+	return d.client.Close()
+}
@@ -0,0 +1,90 @@
+// Copyright 2022 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sink
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/palantir/tenablesc-metrics/metrics"
+)
+
+func TestDatadogAPI_Write(t *testing.T) {
+	var mu sync.Mutex
+	var requests, forbidden int
+	var accepted []datadogSeries
+	status := []int{http.StatusServiceUnavailable, http.StatusAccepted, http.StatusAccepted}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if r.URL.Path != datadogSeriesPath || r.Header.Get("DD-API-KEY") != "secret" {
+			forbidden++
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		code := status[requests]
+		requests++
+
+		if code == http.StatusAccepted {
+			var payload datadogPayload
+			_ = json.NewDecoder(r.Body).Decode(&payload)
+			accepted = append(accepted, payload.Series...)
+		}
+		w.WriteHeader(code)
+	}))
+	defer server.Close()
+
+	snapshot := metrics.NewSnapshot(time.Unix(1700000000, 0), []metrics.Metric{
+		metrics.NewMetric("collectorFailures", metrics.Counter, 2, map[string]string{"collector": "scanAge"}),
+		metrics.NewMetric("failedUpdate", metrics.Gauge, 1, nil),
+		metrics.NewMetric("scanDurationSeconds", metrics.Distribution, 3600, map[string]string{"scanName": "daily"}).WithUnit("second"),
+	}, nil)
+
+	api, err := NewDatadogAPI(DatadogAPIConfig{URL: server.URL, APIKey: "secret", Tags: []string{"env:test"}, BatchSize: 2, RetryBackoff: time.Millisecond, Interval: 5 * time.Minute})
+	if err != nil {
+		t.Fatalf("NewDatadogAPI() error = %v", err)
+	}
+	if err := api.Write(context.Background(), snapshot); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	// two batches, the first of which was retried once
+	if requests != 3 || len(accepted) != 3 {
+		t.Fatalf("got %d requests delivering %d series, want 3 delivering 3", requests, len(accepted))
+	}
+	failures := accepted[0]
+	if failures.Metric != "tenablesc.collectorFailures" || failures.Type != datadogCountType || failures.Interval != 300 || failures.Points[0].Timestamp != 1700000000 {
+		t.Errorf("collectorFailures = %+v", failures)
+	}
+	if durations := accepted[2]; durations.Type != datadogGaugeType || durations.Interval != 0 || durations.Unit != "second" || len(durations.Tags) != 2 || durations.Tags[0] != "env:test" {
+		t.Errorf("scanDurationSeconds = %+v", durations)
+	}
+
+	// a bad key isn't retried
+	api, err = NewDatadogAPI(DatadogAPIConfig{URL: server.URL, APIKey: "wrong", RetryBackoff: time.Millisecond, Interval: time.Minute})
+	if err != nil {
+		t.Fatalf("NewDatadogAPI() error = %v", err)
+	}
+	if err := api.Write(context.Background(), snapshot); err == nil || forbidden != 1 {
+		t.Errorf("Write() with a bad key = %v after %d requests, want an error without retries", err, forbidden)
+	}
+}
@@ -0,0 +1,129 @@
+// Copyright 2022 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sink
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/DataDog/datadog-go/v5/statsd"
+	"github.com/palantir/tenablesc-metrics/metrics"
+)
+
+func init() {
+	registerType("dogstatsd", func(c Config) (Sink, error) {
+		options := DogStatsDConfig{Address: DefaultDatadogAddress}
+		if err := c.Decode(&options); err != nil {
+			return nil, err
+		}
+		return NewDogStatsD(options)
+	})
+}
+
+// Client side aggregation modes for DogStatsDConfig.
+const (
+	NoAggregation       = "none"
+	BasicAggregation    = "basic"
+	ExtendedAggregation = "extended"
+)
+
+// DogStatsDConfig configures the DogStatsD sink.
+type DogStatsDConfig struct {
+	// Address is host:port for UDP, or unix:///path/to/socket for a Unix domain socket.
+	Address string `yaml:"address"`
+	// Namespace prefixes every metric name; defaults to "tenablesc.".
+	Namespace string `yaml:"namespace"`
+	// Tags are added to every metric.
+	Tags []string `yaml:"tags"`
+	// Aggregation is the client side aggregation mode: none, basic (gauges and counts, the default) or
+	// extended (distributions too, which needs agent 6.25 or 7.25 and later).
+	Aggregation         string        `yaml:"aggregation"`
+	AggregationInterval time.Duration `yaml:"aggregationInterval"`
+}
+
+// DogStatsD sends snapshots with a statsd client directly, so each metric keeps its tags and kind: gauges are
+// gauges, counters are counts and distributions, such as scan durations, are distributions.
+type DogStatsD struct {
+	client *statsd.Client
+}
+
+// NewDogStatsD sets up the client; nothing is sent until the first write.
+func NewDogStatsD(c DogStatsDConfig) (*DogStatsD, error) {
+	namespace := c.Namespace
+	if namespace == "" {
+		namespace = metrics.Prefix
+	}
+	if !strings.HasSuffix(namespace, ".") {
+		namespace += "."
+	}
+
+	options := []statsd.Option{statsd.WithNamespace(namespace), statsd.WithTags(c.Tags)}
+	switch c.Aggregation {
+	case NoAggregation:
+		options = append(options, statsd.WithoutClientSideAggregation())
+	case "", BasicAggregation:
+		options = append(options, statsd.WithClientSideAggregation())
+	case ExtendedAggregation:
+		options = append(options, statsd.WithExtendedClientSideAggregation())
+	default:
+		return nil, fmt.Errorf("unknown dogstatsd aggregation %q", c.Aggregation)
+	}
+	if c.AggregationInterval > 0 {
+		options = append(options, statsd.WithAggregationInterval(c.AggregationInterval))
+	}
+
+	client, err := statsd.New(c.Address, options...)
+	if err != nil {
+		return nil, err
+	}
+	return &DogStatsD{client: client}, nil
+}
+
+func (d *DogStatsD) Name() string {
+	return "dogstatsd"
+}
+
+func (d *DogStatsD) Write(_ context.Context, snapshot *metrics.Snapshot) error {
+	var errs []error
+	for _, m := range snapshot.Metrics() {
+		tags := m.TagStrings()
+
+		var err error
+		switch m.Kind {
+		case metrics.Counter:
+			err = d.client.Count(m.Name, int64(math.Round(m.Value)), tags, 1)
+		case metrics.Distribution:
+			err = d.client.Distribution(m.Name, m.Value, tags, 1)
+		default:
+			err = d.client.Gauge(m.Name, m.Value, tags, 1)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed sending %s: %w", m.Key(), err))
+		}
+	}
+
+	if err := d.client.Flush(); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+func (d *DogStatsD) Close() error {
+	return d.client.Close()
+}
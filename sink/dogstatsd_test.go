@@ -0,0 +1,77 @@
+// Copyright 2022 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sink
+
+import (
+	"context"
+	"net"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/palantir/tenablesc-metrics/metrics"
+)
+
+func TestDogStatsD_Write(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	dogstatsd, err := NewDogStatsD(DogStatsDConfig{
+		Address:     conn.LocalAddr().String(),
+		Tags:        []string{"env:test"},
+		Aggregation: ExtendedAggregation,
+	})
+	if err != nil {
+		t.Fatalf("NewDogStatsD() error = %v", err)
+	}
+	defer func() { _ = dogstatsd.Close() }()
+
+	snapshot := metrics.NewSnapshot(time.Now(), []metrics.Metric{
+		metrics.NewMetric("scanDurationSeconds", metrics.Distribution, 3600, map[string]string{metrics.OrgTagName: "foo", "scanName": "daily"}),
+		metrics.NewMetric("collectorFailures", metrics.Counter, 2, map[string]string{"collector": "scanAge"}),
+		metrics.NewMetric("failedUpdate", metrics.Gauge, 1, nil),
+	}, nil)
+	if err := dogstatsd.Write(context.Background(), snapshot); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	want := []string{
+		"tenablesc.collectorFailures:2|c|#env:test,collector:scanAge",
+		"tenablesc.failedUpdate:1|g|#env:test",
+		"tenablesc.scanDurationSeconds:3600|d|#env:test,org:foo,scanName:daily",
+	}
+
+	var got []string
+	buf := make([]byte, 65536)
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for len(got) < len(want) {
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			t.Fatalf("received %v before %v", got, err)
+		}
+		for _, line := range strings.Split(strings.TrimSpace(string(buf[:n])), "\n") {
+			got = append(got, line)
+		}
+	}
+	sort.Strings(got)
+
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("received\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
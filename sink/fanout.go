@@ -0,0 +1,90 @@
+// Copyright 2022 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sink
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/palantir/tenablesc-metrics/metrics"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	sinkFlushSuccessMetricName      = "sinkFlushSuccess"
+	sinkFlushMillisecondsMetricName = "sinkFlushMilliseconds"
+	sinkTagName                     = "sink"
+)
+
+// Result is the outcome of writing a snapshot to one sink.
+type Result struct {
+	Sink     string
+	Err      error
+	Duration time.Duration
+}
+
+// WriteAll writes the snapshot to every sink concurrently, so a slow or failing sink doesn't hold up the rest.
+// Results are returned in the order of sinks.
+func WriteAll(ctx context.Context, sinks []Sink, snapshot *metrics.Snapshot) []Result {
+	results := make([]Result, len(sinks))
+
+	var wg sync.WaitGroup
+	for i, s := range sinks {
+		wg.Add(1)
+		go func(i int, s Sink) {
+			defer wg.Done()
+
+			start := time.Now()
+			err := s.Write(ctx, snapshot)
+			results[i] = Result{Sink: s.Name(), Err: err, Duration: time.Since(start)}
+
+			if err != nil {
+				log.Error().Err(err).Str("sink", s.Name()).Msg("failed writing to sink")
+			} else {
+				log.Debug().Str("sink", s.Name()).Dur("duration", results[i].Duration).Msg("wrote to sink")
+			}
+		}(i, s)
+	}
+	wg.Wait()
+
+	return results
+}
+
+// ResultMetrics reports whether each sink's write succeeded and how long it took.
+func ResultMetrics(results []Result) []metrics.Metric {
+	var ms []metrics.Metric
+	for _, r := range results {
+		tags := map[string]string{sinkTagName: r.Sink}
+		success := 1.0
+		if r.Err != nil {
+			success = 0
+		}
+		ms = append(ms,
+			metrics.NewMetric(sinkFlushSuccessMetricName, metrics.Gauge, success, tags),
+			metrics.NewMetric(sinkFlushMillisecondsMetricName, metrics.Gauge, float64(r.Duration.Milliseconds()), tags).WithUnit("millisecond"),
+		)
+	}
+	return ms
+}
+
+// CloseAll closes every sink, logging any failures.
+func CloseAll(sinks []Sink) {
+	for _, s := range sinks {
+		if err := s.Close(); err != nil {
+			log.Warn().Err(err).Str("sink", s.Name()).Msg("failed to close sink")
+		}
+	}
+}
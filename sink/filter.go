@@ -0,0 +1,99 @@
+// Copyright 2022 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sink
+
+import (
+	"context"
+	"path"
+
+	"github.com/palantir/tenablesc-metrics/metrics"
+)
+
+// configured applies a sinks list entry's name, filters and tag rewrites in front of the sink it built.
+type configured struct {
+	Sink
+
+	name             string
+	include, exclude []string
+	rewrite          TagRewrite
+}
+
+func (c *configured) Name() string {
+	return c.name
+}
+
+func (c *configured) Write(ctx context.Context, snapshot *metrics.Snapshot) error {
+	if len(c.include) == 0 && len(c.exclude) == 0 && c.rewrite.empty() {
+		return c.Sink.Write(ctx, snapshot)
+	}
+
+	var kept []metrics.Metric
+	for _, m := range snapshot.Metrics() {
+		if !c.allows(m.Name) {
+			continue
+		}
+		kept = append(kept, c.rewrite.apply(m))
+	}
+	return c.Sink.Write(ctx, metrics.NewSnapshot(snapshot.Time(), kept, snapshot.Failed()))
+}
+
+func (c *configured) allows(name string) bool {
+	if len(c.include) > 0 && !matchesAny(c.include, name) {
+		return false
+	}
+	return !matchesAny(c.exclude, name)
+}
+
+func matchesAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		// patterns were checked when the config was validated.
+		if matched, _ := path.Match(pattern, name); matched {
+			return true
+		}
+	}
+	return false
+}
+
+func (r TagRewrite) empty() bool {
+	return len(r.Rename) == 0 && len(r.Drop) == 0 && len(r.Add) == 0
+}
+
+// apply rewrites the tags of m, which must already be a copy the sink owns.
+func (r TagRewrite) apply(m metrics.Metric) metrics.Metric {
+	if r.empty() {
+		return m
+	}
+
+	tags := make(map[string]string, len(m.Tags)+len(r.Add))
+	for k, v := range m.Tags {
+		if renamed, ok := r.Rename[k]; ok {
+			k = renamed
+		}
+		tags[k] = v
+	}
+	for _, k := range r.Drop {
+		delete(tags, k)
+	}
+	for k, v := range r.Add {
+		tags[k] = v
+	}
+
+	rewritten := metrics.NewMetric(m.Name, m.Kind, m.Value, tags)
+	rewritten.Unit = m.Unit
+	rewritten.Timestamp = m.Timestamp
+	rewritten.Collector = m.Collector
+	rewritten.Org = m.Org
+	return rewritten
+}
@@ -0,0 +1,219 @@
+// Copyright 2022 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sink
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/palantir/tenablesc-metrics/metrics"
+)
+
+func init() {
+	registerType("graphite", func(c Config) (Sink, error) {
+		var options GraphiteConfig
+		if err := c.Decode(&options); err != nil {
+			return nil, err
+		}
+		return NewGraphite(options)
+	})
+}
+
+const (
+	defaultGraphitePort     = "2003"
+	defaultGraphiteTemplate = "{name}"
+	defaultGraphiteTimeout  = 10 * time.Second
+)
+
+// GraphiteConfig configures the Graphite sink; it is disabled unless Address is set.
+type GraphiteConfig struct {
+	// Address is the host:port of the plaintext listener; the port defaults to 2003.
+	Address string `yaml:"address"`
+	// Prefix is the first path segment; defaults to tenablesc.
+	Prefix string `yaml:"prefix"`
+	// Template lays out the path after the prefix from dot separated segments, where {name} is the metric name
+	// and {<tag>} is the value of that tag, such as "{org}.{name}.{scanName}". Defaults to {name}.
+	Template string `yaml:"template"`
+	// Tagged sends series as name;tag=value instead of folding tags into the path, for Graphite 1.1+.
+	Tagged  bool          `yaml:"tagged"`
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+// Graphite sends snapshots over the Graphite plaintext protocol, one connection per cycle.
+type Graphite struct {
+	config   GraphiteConfig
+	template []string
+}
+
+var templateSegmentPattern = regexp.MustCompile(`^\{([A-Za-z0-9_]+)\}$|^[A-Za-z0-9_-]+$`)
+
+// NewGraphite validates the config and its template.
+func NewGraphite(c GraphiteConfig) (*Graphite, error) {
+	if c.Address == "" {
+		return nil, fmt.Errorf("no graphite address set")
+	}
+	if _, _, err := net.SplitHostPort(c.Address); err != nil {
+		c.Address = net.JoinHostPort(c.Address, defaultGraphitePort)
+	}
+	if c.Prefix == "" {
+		c.Prefix = metrics.Prefix
+	}
+	if c.Template == "" {
+		c.Template = defaultGraphiteTemplate
+	}
+	if c.Timeout == 0 {
+		c.Timeout = defaultGraphiteTimeout
+	}
+
+	template := strings.Split(c.Template, ".")
+	hasName := false
+	for _, segment := range template {
+		if !templateSegmentPattern.MatchString(segment) {
+			return nil, fmt.Errorf("invalid graphite template segment %q", segment)
+		}
+		hasName = hasName || segment == "{name}"
+	}
+	if !hasName {
+		return nil, fmt.Errorf("graphite template %q must include {name}", c.Template)
+	}
+
+	return &Graphite{config: c, template: template}, nil
+}
+
+func (g *Graphite) Name() string {
+	return "graphite"
+}
+
+func (g *Graphite) Write(ctx context.Context, snapshot *metrics.Snapshot) error {
+	var lines bytes.Buffer
+	for _, m := range snapshot.Metrics() {
+		at := snapshot.Time()
+		if !m.Timestamp.IsZero() {
+			at = m.Timestamp
+		}
+
+		if g.config.Tagged {
+			lines.WriteString(g.taggedName(m))
+		} else {
+			lines.WriteString(g.path(m))
+		}
+		lines.WriteByte(' ')
+		lines.WriteString(strconv.FormatFloat(m.Value, 'g', -1, 64))
+		lines.WriteByte(' ')
+		lines.WriteString(strconv.FormatInt(at.Unix(), 10))
+		lines.WriteByte('\n')
+	}
+
+	dialer := net.Dialer{Timeout: g.config.Timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", g.config.Address)
+	if err != nil {
+		return fmt.Errorf("failed to connect to graphite: %w", err)
+	}
+	if err := conn.SetWriteDeadline(time.Now().Add(g.config.Timeout)); err != nil {
+		_ = conn.Close()
+		return err
+	}
+	if _, err := conn.Write(lines.Bytes()); err != nil {
+		_ = conn.Close()
+		return fmt.Errorf("failed writing to graphite: %w", err)
+	}
+	return conn.Close()
+}
+
+func (g *Graphite) Close() error {
+	return nil
+}
+
+// path fills in the template. Segments for tags the metric doesn't have are left out, and tags the template
+// doesn't mention are appended in tag name order, so distinct series never share a path.
+func (g *Graphite) path(m metrics.Metric) string {
+	used := make(map[string]bool)
+	segments := []string{graphiteSegment(g.config.Prefix)}
+
+	for _, segment := range g.template {
+		if !strings.HasPrefix(segment, "{") {
+			segments = append(segments, segment)
+			continue
+		}
+
+		key := strings.Trim(segment, "{}")
+		if key == "name" {
+			segments = append(segments, graphiteSegment(m.Name))
+		} else if value, ok := m.Tags[key]; ok {
+			segments = append(segments, graphiteSegment(value))
+		}
+		used[key] = true
+	}
+
+	var rest []string
+	for k := range m.Tags {
+		if !used[k] {
+			rest = append(rest, k)
+		}
+	}
+	sort.Strings(rest)
+	for _, k := range rest {
+		segments = append(segments, graphiteSegment(m.Tags[k]))
+	}
+
+	return strings.Join(segments, ".")
+}
+
+func (g *Graphite) taggedName(m metrics.Metric) string {
+	var b strings.Builder
+	b.WriteString(graphiteSegment(g.config.Prefix))
+	b.WriteByte('.')
+	b.WriteString(graphiteSegment(m.Name))
+
+	keys := make([]string, 0, len(m.Tags))
+	for k := range m.Tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		b.WriteByte(';')
+		b.WriteString(graphiteSegment(k))
+		b.WriteByte('=')
+		b.WriteString(graphiteTagValue(m.Tags[k]))
+	}
+	return b.String()
+}
+
+var (
+	unsafeSegmentPattern  = regexp.MustCompile(`[^A-Za-z0-9_-]+`)
+	unsafeTagValuePattern = regexp.MustCompile(`[;\s]+`)
+)
+
+// graphiteSegment replaces anything but letters, digits, underscores and dashes, so values such as scan names
+// can't add path levels.
+func graphiteSegment(s string) string {
+	return unsafeSegmentPattern.ReplaceAllString(s, "_")
+}
+
+// graphiteTagValue replaces the characters Graphite doesn't allow in tag values.
+func graphiteTagValue(s string) string {
+	s = unsafeTagValuePattern.ReplaceAllString(s, "_")
+	if strings.HasPrefix(s, "~") {
+		s = "_" + s[1:]
+	}
+	return s
+}
@@ -0,0 +1,88 @@
+// Copyright 2022 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sink
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/palantir/tenablesc-metrics/metrics"
+)
+
+func TestGraphite_Write(t *testing.T) {
+	snapshot := metrics.NewSnapshot(time.Unix(1700000000, 0), []metrics.Metric{
+		metrics.NewMetric("minutesSinceLastScan", metrics.Gauge, 42, map[string]string{metrics.OrgTagName: "Security Org", "scanName": "weekly.full"}),
+		metrics.NewMetric("jobQueueLength", metrics.Gauge, 3, map[string]string{"jobType": "scan;x"}),
+	}, nil)
+
+	tests := []struct {
+		name   string
+		config GraphiteConfig
+		want   string
+	}{
+		{
+			name:   "template",
+			config: GraphiteConfig{Template: "{org}.sc.{name}"},
+			want: "tenablesc.sc.jobQueueLength.scan_x 3 1700000000\n" +
+				"tenablesc.Security_Org.sc.minutesSinceLastScan.weekly_full 42 1700000000\n",
+		},
+		{
+			name:   "tagged",
+			config: GraphiteConfig{Prefix: "legacy", Tagged: true},
+			want: "legacy.jobQueueLength;jobType=scan_x 3 1700000000\n" +
+				"legacy.minutesSinceLastScan;org=Security_Org;scanName=weekly.full 42 1700000000\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			listener, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer func() { _ = listener.Close() }()
+
+			received := make(chan string, 1)
+			go func() {
+				conn, err := listener.Accept()
+				if err != nil {
+					received <- err.Error()
+					return
+				}
+				data, _ := io.ReadAll(conn)
+				_ = conn.Close()
+				received <- string(data)
+			}()
+
+			tt.config.Address = listener.Addr().String()
+			graphite, err := NewGraphite(tt.config)
+			if err != nil {
+				t.Fatalf("NewGraphite() error = %v", err)
+			}
+			if err := graphite.Write(context.Background(), snapshot); err != nil {
+				t.Fatalf("Write() error = %v", err)
+			}
+			if got := <-received; got != tt.want {
+				t.Errorf("received\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := NewGraphite(GraphiteConfig{Address: "graphite.local", Template: "{org}.bad segment"}); err == nil {
+		t.Errorf("NewGraphite() accepted an invalid template")
+	}
+}
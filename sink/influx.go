@@ -0,0 +1,200 @@
+// Copyright 2022 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sink
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/palantir/tenablesc-metrics/metrics"
+)
+
+func init() {
+	registerType("influx", func(c Config) (Sink, error) {
+		var options InfluxConfig
+		if err := c.Decode(&options); err != nil {
+			return nil, err
+		}
+		return NewInflux(options)
+	})
+}
+
+const (
+	influxWritePath      = "/api/v2/write"
+	influxValueField     = "value"
+	defaultInfluxTimeout = 10 * time.Second
+)
+
+// InfluxConfig configures the InfluxDB sink; it is disabled unless File or URL is set.
+type InfluxConfig struct {
+	// File appends each cycle to a file, or writes it to stdout if "-".
+	File string `yaml:"file"`
+	// URL is the base URL of an InfluxDB v2 server to write each cycle to.
+	URL    string `yaml:"url"`
+	Org    string `yaml:"org"`
+	Bucket string `yaml:"bucket"`
+	// Token is the API token; TokenFile may name a file holding it instead.
+	Token     string        `yaml:"token"`
+	TokenFile string        `yaml:"tokenFile"`
+	Timeout   time.Duration `yaml:"timeout"`
+}
+
+// Influx writes snapshots as InfluxDB line protocol. Each metric name is a measurement with a single value
+// field, and tags are Influx tags.
+type Influx struct {
+	config     InfluxConfig
+	token      string
+	httpClient *http.Client
+}
+
+// NewInflux validates the config and reads the token if it is in a file.
+func NewInflux(c InfluxConfig) (*Influx, error) {
+	if (c.File == "") == (c.URL == "") {
+		return nil, fmt.Errorf("exactly one of influx file or url must be set")
+	}
+	if c.Timeout == 0 {
+		c.Timeout = defaultInfluxTimeout
+	}
+
+	i := &Influx{config: c, token: c.Token}
+	if c.URL != "" {
+		if c.Bucket == "" {
+			return nil, fmt.Errorf("no influx bucket set")
+		}
+		if c.TokenFile != "" {
+			token, err := os.ReadFile(c.TokenFile)
+			if err != nil {
+				return nil, fmt.Errorf("failed reading influx token file: %w", err)
+			}
+			i.token = strings.TrimSpace(string(token))
+		}
+		i.httpClient = &http.Client{Timeout: c.Timeout}
+	}
+
+	return i, nil
+}
+
+func (i *Influx) Name() string {
+	return "influx"
+}
+
+func (i *Influx) Write(ctx context.Context, snapshot *metrics.Snapshot) error {
+	var lines bytes.Buffer
+	writeLineProtocol(&lines, snapshot)
+
+	switch {
+	case i.config.File == "-":
+		_, err := os.Stdout.Write(lines.Bytes())
+		return err
+	case i.config.File != "":
+		return appendToFile(i.config.File, lines.Bytes())
+	default:
+		return i.post(ctx, lines.Bytes())
+	}
+}
+
+func (i *Influx) post(ctx context.Context, body []byte) error {
+	query := url.Values{}
+	query.Set("org", i.config.Org)
+	query.Set("bucket", i.config.Bucket)
+	query.Set("precision", "ns")
+	writeURL := strings.TrimSuffix(i.config.URL, "/") + influxWritePath + "?" + query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, writeURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	if i.token != "" {
+		req.Header.Set("Authorization", "Token "+i.token)
+	}
+
+	resp, err := i.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("influx write failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("influx write failed with status %d: %s", resp.StatusCode, respBody)
+	}
+	return nil
+}
+
+func (i *Influx) Close() error {
+	return nil
+}
+
+func appendToFile(path string, data []byte) error {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// writeLineProtocol writes one line per series, timestamped with the source timestamp if known and the
+// collection time otherwise.
+func writeLineProtocol(w *bytes.Buffer, snapshot *metrics.Snapshot) {
+	for _, m := range snapshot.Metrics() {
+		at := snapshot.Time()
+		if !m.Timestamp.IsZero() {
+			at = m.Timestamp
+		}
+
+		w.WriteString(measurementEscaper.Replace(m.Name))
+
+		keys := make([]string, 0, len(m.Tags))
+		for k := range m.Tags {
+			keys = append(keys, k)
+		}
+		// Influx wants tags sorted by key for the best write performance.
+		sort.Strings(keys)
+		for _, k := range keys {
+			w.WriteByte(',')
+			w.WriteString(tagEscaper.Replace(k))
+			w.WriteByte('=')
+			w.WriteString(tagEscaper.Replace(m.Tags[k]))
+		}
+
+		w.WriteByte(' ')
+		w.WriteString(influxValueField)
+		w.WriteByte('=')
+		w.WriteString(strconv.FormatFloat(m.Value, 'g', -1, 64))
+		w.WriteByte(' ')
+		w.WriteString(strconv.FormatInt(at.UnixNano(), 10))
+		w.WriteByte('\n')
+	}
+}
+
+// Line protocol has no escape for newlines, so they are written as escaped spaces.
+var (
+	measurementEscaper = strings.NewReplacer(`\`, `\\`, ",", `\,`, " ", `\ `, "\n", `\ `)
+	tagEscaper         = strings.NewReplacer(`\`, `\\`, ",", `\,`, "=", `\=`, " ", `\ `, "\n", `\ `)
+)
@@ -0,0 +1,66 @@
+// Copyright 2022 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sink
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/palantir/tenablesc-metrics/metrics"
+)
+
+func TestInflux_Write(t *testing.T) {
+	collected := time.Unix(1700000000, 0)
+	finished := time.Unix(1699990000, 0)
+	snapshot := metrics.NewSnapshot(collected, []metrics.Metric{
+		metrics.NewMetric("minutesSinceLastScan", metrics.Gauge, 42, map[string]string{metrics.OrgTagName: "foo", "scanName": "weekly full, all"}),
+		metrics.NewMetric("minutesSinceLastScan", metrics.Gauge, 7, map[string]string{metrics.OrgTagName: "foo", "scanName": "C:\\scans\\nightly\n"}),
+		metrics.NewMetric("scanDurationSeconds", metrics.Distribution, 3600, map[string]string{metrics.OrgTagName: "foo", "scanName": "daily"}).WithTimestamp(finished),
+		metrics.NewMetric("failedUpdate", metrics.Gauge, 0, nil),
+	}, nil)
+
+	want := `failedUpdate value=0 1700000000000000000
+minutesSinceLastScan,org=foo,scanName=C:\\scans\\nightly\  value=7 1700000000000000000
+minutesSinceLastScan,org=foo,scanName=weekly\ full\,\ all value=42 1700000000000000000
+scanDurationSeconds,org=foo,scanName=daily value=3600 1699990000000000000
+`
+
+	var gotBody, gotQuery, gotAuth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		gotBody, gotQuery, gotAuth = string(body), r.URL.RawQuery, r.Header.Get("Authorization")
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	influx, err := NewInflux(InfluxConfig{URL: server.URL, Org: "security", Bucket: "tenablesc", Token: "secret"})
+	if err != nil {
+		t.Fatalf("NewInflux() error = %v", err)
+	}
+	if err := influx.Write(context.Background(), snapshot); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	if gotBody != want {
+		t.Errorf("body =\n%s\nwant\n%s", gotBody, want)
+	}
+	if gotQuery != "bucket=tenablesc&org=security&precision=ns" || gotAuth != "Token secret" {
+		t.Errorf("query = %q, authorization = %q", gotQuery, gotAuth)
+	}
+}
@@ -0,0 +1,317 @@
+// Copyright 2022 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sink
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/palantir/tenablesc-metrics/metrics"
+	collectorpb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+)
+
+func init() {
+	registerType("otlp", func(c Config) (Sink, error) {
+		var options OTLPConfig
+		if err := c.Decode(&options); err != nil {
+			return nil, err
+		}
+		return NewOTLP(options)
+	})
+}
+
+const (
+	// OTLPGRPC sends to an OTLP/gRPC receiver, conventionally on port 4317.
+	OTLPGRPC = "grpc"
+	// OTLPHTTP sends to an OTLP/HTTP receiver with protobuf bodies, conventionally on port 4318.
+	OTLPHTTP = "http/protobuf"
+
+	otlpScopeName       = "github.com/palantir/tenablesc-metrics"
+	otlpServiceName     = "tenablesc-metrics"
+	otlpMetricsPath     = "/v1/metrics"
+	defaultOTLPTimeout  = 10 * time.Second
+	protobufContentType = "application/x-protobuf"
+)
+
+// OTLPConfig configures the OTLP sink; it is disabled unless Endpoint is set.
+type OTLPConfig struct {
+	// Endpoint is host:port for gRPC, or the base URL of the receiver for HTTP.
+	Endpoint string `yaml:"endpoint"`
+	// Protocol is grpc (default) or http/protobuf.
+	Protocol string `yaml:"protocol"`
+	// Insecure disables TLS for gRPC; for HTTP the URL's scheme decides.
+	Insecure bool              `yaml:"insecure"`
+	Headers  map[string]string `yaml:"headers"`
+	// ResourceAttributes are added to the resource, alongside service.name.
+	ResourceAttributes map[string]string `yaml:"resourceAttributes"`
+	Timeout            time.Duration     `yaml:"timeout"`
+}
+
+// OTLP exports snapshots to an OpenTelemetry collector. Tags become data point attributes, and gauges and
+// distributions are exported as gauges while counters are exported as monotonic delta sums covering the cycle.
+type OTLP struct {
+	config   OTLPConfig
+	resource *resourcepb.Resource
+
+	conn       *grpc.ClientConn
+	grpcClient collectorpb.MetricsServiceClient
+	httpClient *http.Client
+
+	// lastWrite starts the delta window of the next cycle's counters.
+	lastWrite time.Time
+}
+
+// NewOTLP sets up the OTLP sink; gRPC connections are established lazily.
+func NewOTLP(c OTLPConfig) (*OTLP, error) {
+	if c.Endpoint == "" {
+		return nil, fmt.Errorf("no otlp endpoint set")
+	}
+	if c.Timeout == 0 {
+		c.Timeout = defaultOTLPTimeout
+	}
+
+	o := &OTLP{
+		config:   c,
+		resource: otlpResource(c.ResourceAttributes),
+	}
+
+	switch c.Protocol {
+	case "", OTLPGRPC:
+		o.config.Protocol = OTLPGRPC
+		creds := credentials.NewTLS(&tls.Config{MinVersion: tls.VersionTLS12})
+		if c.Insecure {
+			creds = insecure.NewCredentials()
+		}
+		conn, err := grpc.Dial(c.Endpoint, grpc.WithTransportCredentials(creds))
+		if err != nil {
+			return nil, fmt.Errorf("failed to set up otlp connection: %w", err)
+		}
+		o.conn = conn
+		o.grpcClient = collectorpb.NewMetricsServiceClient(conn)
+	case OTLPHTTP:
+		if !strings.HasPrefix(c.Endpoint, "http://") && !strings.HasPrefix(c.Endpoint, "https://") {
+			return nil, fmt.Errorf("otlp http endpoint %q must be a URL", c.Endpoint)
+		}
+		o.httpClient = &http.Client{Timeout: c.Timeout}
+	default:
+		return nil, fmt.Errorf("unknown otlp protocol %q", c.Protocol)
+	}
+
+	return o, nil
+}
+
+func (o *OTLP) Name() string {
+	return "otlp"
+}
+
+func (o *OTLP) Write(ctx context.Context, snapshot *metrics.Snapshot) error {
+	start := o.lastWrite
+	if start.IsZero() {
+		start = snapshot.Time()
+	}
+	request := otlpRequest(o.resource, snapshot, start)
+
+	var err error
+	if o.config.Protocol == OTLPGRPC {
+		err = o.exportGRPC(ctx, request)
+	} else {
+		err = o.exportHTTP(ctx, request)
+	}
+	if err != nil {
+		return err
+	}
+
+	o.lastWrite = snapshot.Time()
+	return nil
+}
+
+func (o *OTLP) exportGRPC(ctx context.Context, request *collectorpb.ExportMetricsServiceRequest) error {
+	ctx, cancel := context.WithTimeout(ctx, o.config.Timeout)
+	defer cancel()
+	if len(o.config.Headers) > 0 {
+		ctx = metadata.NewOutgoingContext(ctx, metadata.New(o.config.Headers))
+	}
+
+	response, err := o.grpcClient.Export(ctx, request)
+	if err != nil {
+		return fmt.Errorf("otlp export failed: %w", err)
+	}
+	return partialSuccessError(response)
+}
+
+func (o *OTLP) exportHTTP(ctx context.Context, request *collectorpb.ExportMetricsServiceRequest) error {
+	body, err := proto.Marshal(request)
+	if err != nil {
+		return err
+	}
+
+	url := strings.TrimSuffix(o.config.Endpoint, "/")
+	if !strings.HasSuffix(url, otlpMetricsPath) {
+		url += otlpMetricsPath
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", protobufContentType)
+	for k, v := range o.config.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := o.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("otlp export failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed reading otlp response: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("otlp export failed with status %d: %s", resp.StatusCode, respBody)
+	}
+
+	var response collectorpb.ExportMetricsServiceResponse
+	if err := proto.Unmarshal(respBody, &response); err != nil {
+		// an empty or unparseable body on success still means the data was accepted.
+		return nil
+	}
+	return partialSuccessError(&response)
+}
+
+func (o *OTLP) Close() error {
+	if o.conn != nil {
+		return o.conn.Close()
+	}
+	return nil
+}
+
+func partialSuccessError(response *collectorpb.ExportMetricsServiceResponse) error {
+	if partial := response.GetPartialSuccess(); partial.GetRejectedDataPoints() > 0 {
+		return fmt.Errorf("otlp receiver rejected %d data points: %s", partial.GetRejectedDataPoints(), partial.GetErrorMessage())
+	}
+	return nil
+}
+
+func otlpResource(attributes map[string]string) *resourcepb.Resource {
+	all := map[string]string{"service.name": otlpServiceName}
+	for k, v := range attributes {
+		all[k] = v
+	}
+	return &resourcepb.Resource{Attributes: otlpAttributes(all)}
+}
+
+// otlpRequest groups the snapshot's series into one OTLP metric per name.
+func otlpRequest(resource *resourcepb.Resource, snapshot *metrics.Snapshot, deltaStart time.Time) *collectorpb.ExportMetricsServiceRequest {
+	byName := make(map[string]*metricspb.Metric)
+	var names []string
+
+	for _, m := range snapshot.Metrics() {
+		name := metrics.Prefix + "." + m.Name
+		at := snapshot.Time()
+		if !m.Timestamp.IsZero() {
+			at = m.Timestamp
+		}
+		point := &metricspb.NumberDataPoint{
+			Attributes:   otlpAttributes(m.Tags),
+			TimeUnixNano: uint64(at.UnixNano()),
+			Value:        &metricspb.NumberDataPoint_AsDouble{AsDouble: m.Value},
+		}
+
+		metric, ok := byName[name]
+		if !ok {
+			metric = &metricspb.Metric{Name: name, Unit: otlpUnit(m.Unit)}
+			if m.Kind == metrics.Counter {
+				metric.Data = &metricspb.Metric_Sum{Sum: &metricspb.Sum{
+					AggregationTemporality: metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_DELTA,
+					IsMonotonic:            true,
+				}}
+			} else {
+				metric.Data = &metricspb.Metric_Gauge{Gauge: &metricspb.Gauge{}}
+			}
+			byName[name] = metric
+			names = append(names, name)
+		}
+
+		switch data := metric.Data.(type) {
+		case *metricspb.Metric_Sum:
+			point.StartTimeUnixNano = uint64(deltaStart.UnixNano())
+			data.Sum.DataPoints = append(data.Sum.DataPoints, point)
+		case *metricspb.Metric_Gauge:
+			data.Gauge.DataPoints = append(data.Gauge.DataPoints, point)
+		}
+	}
+
+	scope := &metricspb.ScopeMetrics{Scope: &commonpb.InstrumentationScope{Name: otlpScopeName}}
+	for _, name := range names {
+		scope.Metrics = append(scope.Metrics, byName[name])
+	}
+
+	return &collectorpb.ExportMetricsServiceRequest{
+		ResourceMetrics: []*metricspb.ResourceMetrics{{
+			Resource:     resource,
+			ScopeMetrics: []*metricspb.ScopeMetrics{scope},
+		}},
+	}
+}
+
+func otlpAttributes(tags map[string]string) []*commonpb.KeyValue {
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	attributes := make([]*commonpb.KeyValue, 0, len(keys))
+	for _, k := range keys {
+		attributes = append(attributes, &commonpb.KeyValue{
+			Key:   k,
+			Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: tags[k]}},
+		})
+	}
+	return attributes
+}
+
+// otlpUnit converts our units to the UCUM codes OTLP expects.
+func otlpUnit(unit string) string {
+	switch unit {
+	case "second":
+		return "s"
+	case "minute":
+		return "min"
+	case "millisecond":
+		return "ms"
+	case "day":
+		return "d"
+	default:
+		return unit
+	}
+}
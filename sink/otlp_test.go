@@ -0,0 +1,152 @@
+// Copyright 2022 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sink
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/palantir/tenablesc-metrics/metrics"
+	collectorpb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+)
+
+// otlpReceiver stands in for an OpenTelemetry collector, keeping the last request it received.
+type otlpReceiver struct {
+	collectorpb.UnimplementedMetricsServiceServer
+
+	request *collectorpb.ExportMetricsServiceRequest
+	headers metadata.MD
+}
+
+func (r *otlpReceiver) Export(ctx context.Context, request *collectorpb.ExportMetricsServiceRequest) (*collectorpb.ExportMetricsServiceResponse, error) {
+	r.request = request
+	r.headers, _ = metadata.FromIncomingContext(ctx)
+	return &collectorpb.ExportMetricsServiceResponse{}, nil
+}
+
+func otlpTestSnapshot() *metrics.Snapshot {
+	return metrics.NewSnapshot(time.Unix(1700000000, 0), []metrics.Metric{
+		metrics.NewMetric("scanDurationSeconds", metrics.Distribution, 3600, map[string]string{metrics.OrgTagName: "foo", "scanName": "daily"}).WithUnit("second"),
+		metrics.NewMetric("collectorFailures", metrics.Counter, 2, map[string]string{"collector": "scanAge"}),
+	}, nil)
+}
+
+func checkOTLPRequest(t *testing.T, request *collectorpb.ExportMetricsServiceRequest) {
+	t.Helper()
+
+	if len(request.GetResourceMetrics()) != 1 {
+		t.Fatalf("got %d resources, want 1", len(request.GetResourceMetrics()))
+	}
+	resource := request.GetResourceMetrics()[0]
+
+	attributes := make(map[string]string)
+	for _, kv := range resource.GetResource().GetAttributes() {
+		attributes[kv.GetKey()] = kv.GetValue().GetStringValue()
+	}
+	if attributes["service.name"] != otlpServiceName || attributes["deployment.environment"] != "test" {
+		t.Errorf("resource attributes = %v", attributes)
+	}
+
+	ms := resource.GetScopeMetrics()[0].GetMetrics()
+	if len(ms) != 2 {
+		t.Fatalf("got %d metrics, want 2", len(ms))
+	}
+
+	failures := ms[0]
+	if failures.GetName() != "tenablesc.collectorFailures" || !failures.GetSum().GetIsMonotonic() || failures.GetSum().GetDataPoints()[0].GetAsDouble() != 2 {
+		t.Errorf("collectorFailures = %v, want a monotonic sum of 2", failures)
+	}
+
+	durations := ms[1]
+	point := durations.GetGauge().GetDataPoints()[0]
+	if durations.GetName() != "tenablesc.scanDurationSeconds" || durations.GetUnit() != "s" || point.GetAsDouble() != 3600 {
+		t.Errorf("scanDurationSeconds = %v, want a gauge of 3600s", durations)
+	}
+	if len(point.GetAttributes()) != 2 || point.GetAttributes()[0].GetKey() != metrics.OrgTagName {
+		t.Errorf("scanDurationSeconds attributes = %v, want org and scanName", point.GetAttributes())
+	}
+}
+
+func TestOTLP_WriteGRPC(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	receiver := &otlpReceiver{}
+	server := grpc.NewServer()
+	collectorpb.RegisterMetricsServiceServer(server, receiver)
+	go func() { _ = server.Serve(listener) }()
+	defer server.Stop()
+
+	otlp, err := NewOTLP(OTLPConfig{
+		Endpoint:           listener.Addr().String(),
+		Insecure:           true,
+		Headers:            map[string]string{"x-api-key": "secret"},
+		ResourceAttributes: map[string]string{"deployment.environment": "test"},
+	})
+	if err != nil {
+		t.Fatalf("NewOTLP() error = %v", err)
+	}
+	defer func() { _ = otlp.Close() }()
+
+	if err := otlp.Write(context.Background(), otlpTestSnapshot()); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	checkOTLPRequest(t, receiver.request)
+	if got := receiver.headers.Get("x-api-key"); len(got) != 1 || got[0] != "secret" {
+		t.Errorf("headers = %v, want x-api-key", receiver.headers)
+	}
+}
+
+func TestOTLP_WriteHTTP(t *testing.T) {
+	var request collectorpb.ExportMetricsServiceRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != otlpMetricsPath || r.Header.Get("Content-Type") != protobufContentType {
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		if err := proto.Unmarshal(body, &request); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", protobufContentType)
+		response, _ := proto.Marshal(&collectorpb.ExportMetricsServiceResponse{})
+		_, _ = w.Write(response)
+	}))
+	defer server.Close()
+
+	otlp, err := NewOTLP(OTLPConfig{
+		Endpoint:           server.URL,
+		Protocol:           OTLPHTTP,
+		ResourceAttributes: map[string]string{"deployment.environment": "test"},
+	})
+	if err != nil {
+		t.Fatalf("NewOTLP() error = %v", err)
+	}
+
+	if err := otlp.Write(context.Background(), otlpTestSnapshot()); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	checkOTLPRequest(t, &request)
+}
@@ -0,0 +1,179 @@
+// Copyright 2022 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sink
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/palantir/go-baseapp/baseapp"
+	"github.com/palantir/tenablesc-metrics/metrics"
+	"github.com/rs/zerolog/log"
+	"goji.io/pat"
+)
+
+func init() {
+	registerType("prometheus", func(c Config) (Sink, error) {
+		options := baseapp.HTTPConfig{Port: DefaultPrometheusPort}
+		if err := c.Decode(&options); err != nil {
+			return nil, err
+		}
+		return NewPrometheus(options)
+	})
+}
+
+const (
+	// DefaultPrometheusPort is where /metrics is served unless configured otherwise.
+	DefaultPrometheusPort = 8080
+
+	lastCollectionSuccessMetricName = "lastCollectionSuccess"
+	snapshotAgeSecondsMetricName    = "snapshotAgeSeconds"
+	prometheusShutdownTimeout       = 5 * time.Second
+)
+
+// Prometheus serves snapshots on /metrics for Prometheus to scrape, on a go-baseapp server.
+//
+// Scrapes see the latest snapshot. Where part of a cycle failed, the series of the sources which failed are carried
+// over from the cycle before, so a broken org or collector doesn't make the rest of its series disappear.
+// lastCollectionSuccess and snapshotAgeSeconds describe the latest snapshot.
+type Prometheus struct {
+	server *baseapp.Server
+
+	mu     sync.RWMutex
+	latest *metrics.Snapshot
+	// collected is the collector series being served: the latest snapshot's plus any carried over.
+	collected []metrics.Metric
+}
+
+// NewPrometheus starts serving on the configured address; it fails straight away if the port is taken.
+func NewPrometheus(c baseapp.HTTPConfig) (*Prometheus, error) {
+	server, err := baseapp.NewServer(c, baseapp.DefaultParams(log.Logger, "")...)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &Prometheus{server: server}
+	server.Mux().Handle(pat.Get("/metrics"), p)
+
+	listener, err := net.Listen("tcp", net.JoinHostPort(c.Address, strconv.Itoa(c.Port)))
+	if err != nil {
+		return nil, err
+	}
+	log.Info().Str("address", listener.Addr().String()).Msg("serving prometheus metrics")
+
+	go func() {
+		var err error
+		if c.TLSConfig != nil {
+			err = server.HTTPServer().ServeTLS(listener, c.TLSConfig.CertFile, c.TLSConfig.KeyFile)
+		} else {
+			err = server.HTTPServer().Serve(listener)
+		}
+		if err != http.ErrServerClosed {
+			log.Error().Err(err).Msg("prometheus server failed")
+		}
+	}()
+
+	return p, nil
+}
+
+func (p *Prometheus) Name() string {
+	return "prometheus"
+}
+
+func (p *Prometheus) Write(_ context.Context, snapshot *metrics.Snapshot) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.collected = carryOver(p.collected, snapshot)
+	p.latest = snapshot
+	return nil
+}
+
+func (p *Prometheus) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), prometheusShutdownTimeout)
+	defer cancel()
+	return p.server.HTTPServer().Shutdown(ctx)
+}
+
+func (p *Prometheus) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	openMetrics := strings.Contains(r.Header.Get("Accept"), "application/openmetrics-text")
+	contentType := metrics.PrometheusContentType
+	if openMetrics {
+		contentType = metrics.OpenMetricsContentType
+	}
+
+	var body bytes.Buffer
+	if err := metrics.WritePrometheus(&body, p.served(), openMetrics); err != nil {
+		log.Error().Err(err).Msg("failed to encode metrics")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	_, _ = w.Write(body.Bytes())
+}
+
+// carryOver returns the snapshot's collector series, plus those of previous whose source failed in the snapshot.
+func carryOver(previous []metrics.Metric, snapshot *metrics.Snapshot) []metrics.Metric {
+	var collected []metrics.Metric
+	present := make(map[string]bool)
+	for _, m := range snapshot.Metrics() {
+		if m.Collector != "" {
+			collected = append(collected, m)
+			present[m.Key()] = true
+		}
+	}
+
+	failed := metrics.NewFailedSources(snapshot.Failed())
+	for _, m := range previous {
+		if !present[m.Key()] && failed.Covers(m) {
+			collected = append(collected, m)
+		}
+	}
+	return collected
+}
+
+func (p *Prometheus) served() []metrics.Metric {
+	p.mu.RLock()
+	latest, collected := p.latest, p.collected
+	p.mu.RUnlock()
+
+	if latest == nil {
+		return nil
+	}
+
+	var self []metrics.Metric
+	for _, m := range latest.Metrics() {
+		if m.Collector == "" {
+			self = append(self, m)
+		}
+	}
+	age := time.Since(latest.Time()).Seconds()
+	self = append(self, metrics.NewMetric(snapshotAgeSecondsMetricName, metrics.Gauge, age, nil).WithUnit("second"))
+
+	success := 1.0
+	if len(latest.Failed()) > 0 {
+		success = 0
+	}
+	self = append(self, metrics.NewMetric(lastCollectionSuccessMetricName, metrics.Gauge, success, nil))
+
+	return metrics.Merge(collected, self)
+}
@@ -0,0 +1,70 @@
+// Copyright 2022 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sink
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/palantir/tenablesc-metrics/metrics"
+)
+
+func TestPrometheus_served(t *testing.T) {
+	scanAge := func(org string, value float64) metrics.Metric {
+		m := metrics.NewMetric("minutesSinceLastScan", metrics.Gauge, value, map[string]string{metrics.OrgTagName: org, "scanName": "daily"})
+		m.Collector, m.Org = "scanAge", org
+		return m
+	}
+
+	p := &Prometheus{}
+	if got := p.served(); got != nil {
+		t.Errorf("served() before any cycle = %v, want nothing", got)
+	}
+
+	// foo's collector fails in the first cycle, so bar is served on its own.
+	cycles := []*metrics.Snapshot{
+		metrics.NewSnapshot(time.Now(), []metrics.Metric{scanAge("bar", 1)}, []metrics.Source{{Collector: "scanAge", Org: "foo"}}),
+		metrics.NewSnapshot(time.Now(), []metrics.Metric{scanAge("foo", 2), scanAge("bar", 3)}, nil),
+		// bar fails partway through the third cycle; its last value is kept while foo moves on.
+		metrics.NewSnapshot(time.Now(), []metrics.Metric{scanAge("foo", 4)}, []metrics.Source{{Collector: "scanAge", Org: "bar"}}),
+	}
+	want := []map[string]float64{
+		{"minutesSinceLastScan[org:bar,scanName:daily]": 1, lastCollectionSuccessMetricName: 0},
+		{"minutesSinceLastScan[org:foo,scanName:daily]": 2, "minutesSinceLastScan[org:bar,scanName:daily]": 3, lastCollectionSuccessMetricName: 1},
+		{"minutesSinceLastScan[org:foo,scanName:daily]": 4, "minutesSinceLastScan[org:bar,scanName:daily]": 3, lastCollectionSuccessMetricName: 0},
+	}
+
+	for i, snapshot := range cycles {
+		if err := p.Write(context.Background(), snapshot); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+
+		got := make(map[string]float64)
+		for _, m := range p.served() {
+			if m.Name != snapshotAgeSecondsMetricName {
+				got[m.Key()] = m.Value
+			}
+		}
+		if len(got) != len(want[i]) {
+			t.Errorf("cycle %d: served %v, want %v", i, got, want[i])
+		}
+		for key, value := range want[i] {
+			if got[key] != value {
+				t.Errorf("cycle %d: %s = %v, want %v", i, key, got[key], value)
+			}
+		}
+	}
+}
@@ -0,0 +1,143 @@
+// Copyright 2022 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sink
+
+import (
+	"context"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/palantir/tenablesc-metrics/metrics"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+func pushTestSnapshot() *metrics.Snapshot {
+	return metrics.NewSnapshot(time.Unix(1700000000, 0), []metrics.Metric{
+		metrics.NewMetric("minutesSinceLastScan", metrics.Gauge, 42, map[string]string{metrics.OrgTagName: "foo", "scanName": "daily"}),
+	}, nil)
+}
+
+func TestPushgateway_Write(t *testing.T) {
+	var method, path, body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		method, path, body = r.Method, r.URL.EscapedPath(), string(b)
+	}))
+	defer server.Close()
+
+	pushgateway, err := NewPushgateway(PushgatewayConfig{URL: server.URL, Instance: "cron-1", Grouping: map[string]string{"site": "eu/west"}})
+	if err != nil {
+		t.Fatalf("NewPushgateway() error = %v", err)
+	}
+	if err := pushgateway.Write(context.Background(), pushTestSnapshot()); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	if method != http.MethodPut || path != "/metrics/job/tenablesc-metrics/instance/cron-1/site@base64/ZXUvd2VzdA" {
+		t.Errorf("pushed with %s %s", method, path)
+	}
+	if !strings.Contains(body, `tenablesc_minutesSinceLastScan{org="foo",scanName="daily"} 42`) {
+		t.Errorf("pushed body %q", body)
+	}
+}
+
+func TestRemoteWrite_Write(t *testing.T) {
+	var request []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Encoding") != "snappy" || r.Header.Get("X-Prometheus-Remote-Write-Version") == "" {
+			http.Error(w, "not remote write", http.StatusBadRequest)
+			return
+		}
+		compressed, _ := io.ReadAll(r.Body)
+		var err error
+		if request, err = snappy.Decode(nil, compressed); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+
+	remoteWrite, err := NewRemoteWrite(RemoteWriteConfig{URL: server.URL, Instance: "cron-1"})
+	if err != nil {
+		t.Fatalf("NewRemoteWrite() error = %v", err)
+	}
+	if err := remoteWrite.Write(context.Background(), pushTestSnapshot()); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	labels, value, timestamp := firstTimeSeries(t, request)
+	want := "__name__=tenablesc_minutesSinceLastScan,instance=cron-1,job=tenablesc-metrics,org=foo,scanName=daily"
+	if got := strings.Join(labels, ","); got != want {
+		t.Errorf("labels = %s, want %s", got, want)
+	}
+	if value != 42 || timestamp != 1700000000000 {
+		t.Errorf("sample = %v at %d, want 42 at 1700000000000", value, timestamp)
+	}
+}
+
+// firstTimeSeries decodes the labels, as name=value, and the sample of a WriteRequest's first series.
+func firstTimeSeries(t *testing.T, request []byte) (labels []string, value float64, timestamp int64) {
+	t.Helper()
+
+	// WriteRequest.timeseries
+	num, typ, n := protowire.ConsumeTag(request)
+	if num != 1 || typ != protowire.BytesType {
+		t.Fatalf("unexpected field %d of type %d", num, typ)
+	}
+	series, _ := protowire.ConsumeBytes(request[n:])
+
+	for len(series) > 0 {
+		num, _, n := protowire.ConsumeTag(series)
+		field, m := protowire.ConsumeBytes(series[n:])
+		series = series[n+m:]
+
+		switch num {
+		case 1:
+			_, _, n := protowire.ConsumeTag(field)
+			name, m := protowire.ConsumeString(field[n:])
+			_, _, o := protowire.ConsumeTag(field[n+m:])
+			labelValue, _ := protowire.ConsumeString(field[n+m+o:])
+			labels = append(labels, name+"="+labelValue)
+		case 2:
+			_, _, n := protowire.ConsumeTag(field)
+			bits, m := protowire.ConsumeFixed64(field[n:])
+			_, _, o := protowire.ConsumeTag(field[n+m:])
+			ts, _ := protowire.ConsumeVarint(field[n+m+o:])
+			value, timestamp = math.Float64frombits(bits), int64(ts)
+		}
+	}
+	return labels, value, timestamp
+}
+
+func TestRemoteWrite_writeRequestLabelClash(t *testing.T) {
+	remoteWrite, err := NewRemoteWrite(RemoteWriteConfig{URL: "http://prometheus:9090/api/v1/write", Instance: "cron-1"})
+	if err != nil {
+		t.Fatalf("NewRemoteWrite() error = %v", err)
+	}
+	snapshot := metrics.NewSnapshot(time.Unix(1700000000, 0), []metrics.Metric{
+		metrics.NewMetric("scanDurationSeconds", metrics.Gauge, 60, map[string]string{"job": "nightly", "instance": "scanner-1"}),
+	}, nil)
+
+	labels, _, _ := firstTimeSeries(t, remoteWrite.writeRequest(snapshot))
+	want := "__name__=tenablesc_scanDurationSeconds,exported_instance=scanner-1,exported_job=nightly,instance=cron-1,job=tenablesc-metrics"
+	if got := strings.Join(labels, ","); got != want {
+		t.Errorf("labels = %s, want %s", got, want)
+	}
+}
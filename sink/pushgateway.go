@@ -0,0 +1,161 @@
+// Copyright 2022 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sink
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/palantir/tenablesc-metrics/metrics"
+)
+
+func init() {
+	registerType("pushgateway", func(c Config) (Sink, error) {
+		var options PushgatewayConfig
+		if err := c.Decode(&options); err != nil {
+			return nil, err
+		}
+		return NewPushgateway(options)
+	})
+}
+
+const (
+	// DefaultPushJob is the job label pushed metrics are grouped under unless configured otherwise.
+	DefaultPushJob = "tenablesc-metrics"
+
+	defaultPushTimeout = 30 * time.Second
+)
+
+// PushgatewayConfig configures the Pushgateway sink.
+type PushgatewayConfig struct {
+	// URL is the base URL of the Pushgateway.
+	URL string `yaml:"url"`
+	// Job and Instance identify the group the metrics are pushed to; they default to tenablesc-metrics and the
+	// host name.
+	Job      string `yaml:"job"`
+	Instance string `yaml:"instance"`
+	// Grouping adds further labels to the group.
+	Grouping map[string]string `yaml:"grouping"`
+	Headers  map[string]string `yaml:"headers"`
+	Timeout  time.Duration     `yaml:"timeout"`
+}
+
+// Pushgateway replaces its group on a Prometheus Pushgateway with each snapshot, so series which went away
+// this cycle don't linger there.
+type Pushgateway struct {
+	url        string
+	headers    map[string]string
+	httpClient *http.Client
+}
+
+// NewPushgateway builds the group's URL from the config.
+func NewPushgateway(c PushgatewayConfig) (*Pushgateway, error) {
+	if c.URL == "" {
+		return nil, fmt.Errorf("no pushgateway url set")
+	}
+	if c.Job == "" {
+		c.Job = DefaultPushJob
+	}
+	if c.Instance == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return nil, fmt.Errorf("no pushgateway instance set, and failed to get the host name: %w", err)
+		}
+		c.Instance = hostname
+	}
+	if c.Timeout == 0 {
+		c.Timeout = defaultPushTimeout
+	}
+
+	grouping := map[string]string{"instance": c.Instance}
+	for k, v := range c.Grouping {
+		grouping[k] = v
+	}
+	if _, ok := grouping["job"]; ok {
+		return nil, fmt.Errorf("set the pushgateway job with job rather than grouping")
+	}
+
+	path := "/metrics/job" + pushPathSegment(c.Job)
+	names := make([]string, 0, len(grouping))
+	for name := range grouping {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		path += "/" + metrics.PrometheusName(name) + pushPathSegment(grouping[name])
+	}
+
+	return &Pushgateway{
+		url:        strings.TrimSuffix(c.URL, "/") + path,
+		headers:    c.Headers,
+		httpClient: &http.Client{Timeout: c.Timeout},
+	}, nil
+}
+
+// pushPathSegment encodes a grouping label value, falling back to base64 for values the path can't hold as is.
+func pushPathSegment(value string) string {
+	if value == "" {
+		return "@base64/="
+	}
+	if strings.Contains(value, "/") {
+		return "@base64/" + base64.RawURLEncoding.EncodeToString([]byte(value))
+	}
+	return "/" + url.PathEscape(value)
+}
+
+func (p *Pushgateway) Name() string {
+	return "pushgateway"
+}
+
+func (p *Pushgateway) Write(ctx context.Context, snapshot *metrics.Snapshot) error {
+	var body bytes.Buffer
+	if err := metrics.WritePrometheus(&body, snapshot.Metrics(), false); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, p.url, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", metrics.PrometheusContentType)
+	for k, v := range p.headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("pushgateway push failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("pushgateway push failed with status %d: %s", resp.StatusCode, respBody)
+	}
+	return nil
+}
+
+func (p *Pushgateway) Close() error {
+	return nil
+}
@@ -0,0 +1,191 @@
+// Copyright 2022 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sink
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/palantir/tenablesc-metrics/metrics"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+func init() {
+	registerType("remote-write", func(c Config) (Sink, error) {
+		var options RemoteWriteConfig
+		if err := c.Decode(&options); err != nil {
+			return nil, err
+		}
+		return NewRemoteWrite(options)
+	})
+}
+
+const (
+	remoteWriteVersion = "0.1.0"
+	metricNameLabel    = "__name__"
+)
+
+// RemoteWriteConfig configures the Prometheus remote_write sink.
+type RemoteWriteConfig struct {
+	// URL is the receiver's full write endpoint, such as http://prometheus:9090/api/v1/write.
+	URL string `yaml:"url"`
+	// Job and Instance are added as labels, since nothing scrapes us to add them; they default to
+	// tenablesc-metrics and the host name.
+	Job      string `yaml:"job"`
+	Instance string `yaml:"instance"`
+	// Headers are added to each request, such as an Authorization header or a tenant id.
+	Headers map[string]string `yaml:"headers"`
+	Timeout time.Duration     `yaml:"timeout"`
+}
+
+// RemoteWrite sends snapshots with the Prometheus remote_write protocol: a snappy compressed protobuf
+// WriteRequest holding one sample per series, stamped with the collection time.
+type RemoteWrite struct {
+	url        string
+	job        string
+	instance   string
+	headers    map[string]string
+	httpClient *http.Client
+}
+
+// NewRemoteWrite validates the config.
+func NewRemoteWrite(c RemoteWriteConfig) (*RemoteWrite, error) {
+	if c.URL == "" {
+		return nil, fmt.Errorf("no remote write url set")
+	}
+	if c.Job == "" {
+		c.Job = DefaultPushJob
+	}
+	if c.Instance == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return nil, fmt.Errorf("no remote write instance set, and failed to get the host name: %w", err)
+		}
+		c.Instance = hostname
+	}
+	if c.Timeout == 0 {
+		c.Timeout = defaultPushTimeout
+	}
+
+	return &RemoteWrite{
+		url:        c.URL,
+		job:        c.Job,
+		instance:   c.Instance,
+		headers:    c.Headers,
+		httpClient: &http.Client{Timeout: c.Timeout},
+	}, nil
+}
+
+func (r *RemoteWrite) Name() string {
+	return "remote-write"
+}
+
+func (r *RemoteWrite) Write(ctx context.Context, snapshot *metrics.Snapshot) error {
+	body := snappy.Encode(nil, r.writeRequest(snapshot))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("Content-Type", protobufContentType)
+	req.Header.Set("X-Prometheus-Remote-Write-Version", remoteWriteVersion)
+	for k, v := range r.headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := r.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("remote write failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("remote write failed with status %d: %s", resp.StatusCode, respBody)
+	}
+	return nil
+}
+
+func (r *RemoteWrite) Close() error {
+	return nil
+}
+
+// writeRequest encodes the prometheus.WriteRequest message by hand; it is small enough that pulling in the
+// Prometheus protobuf definitions isn't worth it.
+//
+//	WriteRequest { repeated TimeSeries timeseries = 1; }
+//	TimeSeries   { repeated Label labels = 1; repeated Sample samples = 2; }
+//	Label        { string name = 1; string value = 2; }
+//	Sample       { double value = 1; int64 timestamp = 2; }
+func (r *RemoteWrite) writeRequest(snapshot *metrics.Snapshot) []byte {
+	timestamp := snapshot.Time().UnixMilli()
+
+	var request []byte
+	for _, m := range snapshot.Metrics() {
+		labels := map[string]string{
+			metricNameLabel: metrics.PrometheusMetricName(m),
+			"job":           r.job,
+			"instance":      r.instance,
+		}
+		// like a Prometheus scrape, tags clashing with the target labels are kept as exported_<name>.
+		for k, v := range m.Tags {
+			name := metrics.PrometheusName(k)
+			if _, clash := labels[name]; clash {
+				name = "exported_" + name
+			}
+			labels[name] = v
+		}
+		// receivers require labels sorted by name.
+		names := make([]string, 0, len(labels))
+		for name := range labels {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		var series []byte
+		for _, name := range names {
+			var label []byte
+			label = protowire.AppendTag(label, 1, protowire.BytesType)
+			label = protowire.AppendString(label, name)
+			label = protowire.AppendTag(label, 2, protowire.BytesType)
+			label = protowire.AppendString(label, labels[name])
+
+			series = protowire.AppendTag(series, 1, protowire.BytesType)
+			series = protowire.AppendBytes(series, label)
+		}
+
+		var sample []byte
+		sample = protowire.AppendTag(sample, 1, protowire.Fixed64Type)
+		sample = protowire.AppendFixed64(sample, math.Float64bits(m.Value))
+		sample = protowire.AppendTag(sample, 2, protowire.VarintType)
+		sample = protowire.AppendVarint(sample, uint64(timestamp))
+
+		series = protowire.AppendTag(series, 2, protowire.BytesType)
+		series = protowire.AppendBytes(series, sample)
+
+		request = protowire.AppendTag(request, 1, protowire.BytesType)
+		request = protowire.AppendBytes(request, series)
+	}
+	return request
+}
@@ -0,0 +1,41 @@
+// Copyright 2022 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sink
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// retry calls attempt until it succeeds, fails in a way it reports isn't retryable, or has been retried
+// maxRetries times, waiting backoff before the first retry and doubling the wait for each one after.
+func retry(ctx context.Context, what string, maxRetries int, backoff time.Duration, attempt func() (retryable bool, err error)) error {
+	for n := 0; ; n++ {
+		retryable, err := attempt()
+		if err == nil || !retryable || n >= maxRetries {
+			return err
+		}
+
+		log.Warn().Err(err).Int("attempt", n+1).Dur("backoff", backoff).Msgf("retrying %s", what)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
@@ -0,0 +1,29 @@
+// Copyright 2022 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package sink delivers collected snapshots to monitoring systems.
+package sink
+
+import (
+	"context"
+
+	"github.com/palantir/tenablesc-metrics/metrics"
+)
+
+// Sink delivers each cycle's snapshot somewhere. Write is called once per cycle and never concurrently.
+type Sink interface {
+	Name() string
+	Write(ctx context.Context, snapshot *metrics.Snapshot) error
+	Close() error
+}
@@ -0,0 +1,96 @@
+// Copyright 2022 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sink
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/palantir/tenablesc-metrics/metrics"
+)
+
+func init() {
+	registerType("textfile", func(c Config) (Sink, error) {
+		var options struct {
+			Directory string `yaml:"directory"`
+			File      string `yaml:"file"`
+		}
+		if err := c.Decode(&options); err != nil {
+			return nil, err
+		}
+		return NewTextfile(options.Directory, options.File)
+	})
+}
+
+// DefaultTextfileName is the file written into the textfile collector directory unless configured otherwise.
+const DefaultTextfileName = "tenablesc.prom"
+
+// Textfile writes snapshots in the Prometheus text format for node_exporter's textfile collector.
+type Textfile struct {
+	path string
+}
+
+// NewTextfile writes to name in directory; node_exporter only reads files ending in .prom.
+func NewTextfile(directory, name string) (*Textfile, error) {
+	if name == "" {
+		name = DefaultTextfileName
+	}
+	if !strings.HasSuffix(name, ".prom") || filepath.Base(name) != name {
+		return nil, fmt.Errorf("textfile name %q must be a plain file name ending in .prom", name)
+	}
+	if info, err := os.Stat(directory); err != nil {
+		return nil, err
+	} else if !info.IsDir() {
+		return nil, fmt.Errorf("textfile directory %s is not a directory", directory)
+	}
+
+	return &Textfile{path: filepath.Join(directory, name)}, nil
+}
+
+func (t *Textfile) Name() string {
+	return "textfile"
+}
+
+// Write replaces the file atomically, so node_exporter never reads a partially written one. The temporary file
+// doesn't end in .prom, so it is ignored if we die before renaming it.
+func (t *Textfile) Write(_ context.Context, snapshot *metrics.Snapshot) error {
+	tmp, err := os.CreateTemp(filepath.Dir(t.path), "."+filepath.Base(t.path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	if err := metrics.WritePrometheus(tmp, snapshot.Metrics(), false); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed writing %s: %w", tmp.Name(), err)
+	}
+	// node_exporter usually runs as a different user to us.
+	if err := tmp.Chmod(0644); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), t.path)
+}
+
+func (t *Textfile) Close() error {
+	return nil
+}
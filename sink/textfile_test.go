@@ -0,0 +1,62 @@
+// Copyright 2022 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sink
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/palantir/tenablesc-metrics/metrics"
+)
+
+func TestTextfile_Write(t *testing.T) {
+	dir := t.TempDir()
+
+	if _, err := NewTextfile(dir, "tenablesc.txt"); err == nil {
+		t.Errorf("NewTextfile() accepted a name node_exporter would ignore")
+	}
+
+	textfile, err := NewTextfile(dir, "")
+	if err != nil {
+		t.Fatalf("NewTextfile() error = %v", err)
+	}
+
+	for _, value := range []float64{1, 2} {
+		snapshot := metrics.NewSnapshot(time.Now(), []metrics.Metric{metrics.NewMetric("failedUpdate", metrics.Gauge, value, nil)}, nil)
+		if err := textfile.Write(context.Background(), snapshot); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name() != DefaultTextfileName {
+		t.Errorf("directory holds %v, want only %s", entries, DefaultTextfileName)
+	}
+
+	contents, err := os.ReadFile(filepath.Join(dir, DefaultTextfileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(contents), "tenablesc_failedUpdate 2\n") {
+		t.Errorf("textfile = %q, want the latest snapshot", contents)
+	}
+}
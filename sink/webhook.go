@@ -0,0 +1,376 @@
+// Copyright 2022 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sink
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/palantir/tenablesc-metrics/metrics"
+)
+
+func init() {
+	registerType("webhook", func(c Config) (Sink, error) {
+		var options WebhookConfig
+		if err := c.Decode(&options); err != nil {
+			return nil, err
+		}
+		return NewWebhook(options)
+	})
+}
+
+// Webhook modes.
+const (
+	// WebhookSnapshotMode posts every snapshot as a single event.
+	WebhookSnapshotMode = "snapshot"
+	// WebhookThresholdsMode only posts an event when a series crosses one of the thresholds, in either direction.
+	WebhookThresholdsMode = "thresholds"
+)
+
+// CloudEvents types posted by the webhook sink.
+const (
+	SnapshotEventType           = "com.palantir.tenablesc-metrics.snapshot"
+	ThresholdCrossedEventType   = "com.palantir.tenablesc-metrics.threshold.crossed"
+	ThresholdRecoveredEventType = "com.palantir.tenablesc-metrics.threshold.recovered"
+)
+
+const (
+	cloudEventsContentType = "application/cloudevents+json"
+
+	defaultWebhookSource          = "tenablesc-metrics"
+	defaultWebhookSignatureHeader = "X-Signature-256"
+	defaultWebhookMaxRetries      = 3
+	defaultWebhookRetryBackoff    = time.Second
+	defaultWebhookTimeout         = 30 * time.Second
+	defaultWebhookFlushTimeout    = 2 * time.Minute
+)
+
+// WebhookConfig configures the webhook sink.
+type WebhookConfig struct {
+	URL string `yaml:"url"`
+	// Mode is snapshot (the default) or thresholds.
+	Mode string `yaml:"mode"`
+	// Thresholds are checked in thresholds mode.
+	Thresholds []WebhookThreshold `yaml:"thresholds"`
+	// Source is the CloudEvents source attribute; defaults to tenablesc-metrics.
+	Source string `yaml:"source"`
+	// Secret, or the contents of SecretFile, signs each request body with HMAC-SHA256.
+	Secret     string `yaml:"secret"`
+	SecretFile string `yaml:"secretFile"`
+	// SignatureHeader carries the signature as sha256=<hex>; defaults to X-Signature-256.
+	SignatureHeader string            `yaml:"signatureHeader"`
+	Headers         map[string]string `yaml:"headers"`
+	// MaxRetries is how many times an event is retried after a network error, 429 or 5xx; defaults to 3.
+	MaxRetries *int `yaml:"maxRetries"`
+	// RetryBackoff is the wait before the first retry, doubling for each one after; defaults to 1s.
+	RetryBackoff time.Duration `yaml:"retryBackoff"`
+	Timeout      time.Duration `yaml:"timeout"`
+	// FlushTimeout bounds all the requests of one write, retries included, so a slow receiver can't hold up the
+	// cycle for every threshold crossing in turn; defaults to 2m. Crossings not sent in time are sent next cycle.
+	FlushTimeout time.Duration `yaml:"flushTimeout"`
+}
+
+// WebhookThreshold is breached by any series of a matching metric whose value is above Above or below Below.
+type WebhookThreshold struct {
+	// Metric is a glob pattern matched against metric names.
+	Metric string `yaml:"metric"`
+	// Tags, if set, must all be present with these values for a series to be checked.
+	Tags  map[string]string `yaml:"tags"`
+	Above *float64          `yaml:"above"`
+	Below *float64          `yaml:"below"`
+}
+
+func (t WebhookThreshold) matches(m metrics.Metric) bool {
+	if matched, _ := path.Match(t.Metric, m.Name); !matched {
+		return false
+	}
+	for k, v := range t.Tags {
+		if m.Tags[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+func (t WebhookThreshold) breachedBy(value float64) bool {
+	return (t.Above != nil && value > *t.Above) || (t.Below != nil && value < *t.Below)
+}
+
+// Webhook posts CloudEvents in structured JSON mode, either one per snapshot or one per threshold crossing.
+type Webhook struct {
+	url             string
+	mode            string
+	thresholds      []WebhookThreshold
+	source          string
+	secret          []byte
+	signatureHeader string
+	headers         map[string]string
+	maxRetries      int
+	retryBackoff    time.Duration
+	flushTimeout    time.Duration
+	httpClient      *http.Client
+
+	// breached holds the series currently breaching each threshold, by threshold index and series key.
+	breached []map[string]metrics.Metric
+}
+
+// NewWebhook validates the config and reads the secret if it is in a file.
+func NewWebhook(c WebhookConfig) (*Webhook, error) {
+	w := &Webhook{
+		url:             c.URL,
+		mode:            c.Mode,
+		thresholds:      c.Thresholds,
+		source:          c.Source,
+		secret:          []byte(c.Secret),
+		signatureHeader: c.SignatureHeader,
+		headers:         c.Headers,
+		maxRetries:      defaultWebhookMaxRetries,
+		retryBackoff:    c.RetryBackoff,
+		flushTimeout:    c.FlushTimeout,
+		httpClient:      &http.Client{Timeout: c.Timeout},
+	}
+
+	if w.url == "" {
+		return nil, fmt.Errorf("no webhook url set")
+	}
+	switch w.mode {
+	case "":
+		w.mode = WebhookSnapshotMode
+	case WebhookSnapshotMode:
+	case WebhookThresholdsMode:
+		if len(w.thresholds) == 0 {
+			return nil, fmt.Errorf("webhook mode %s needs at least one threshold", WebhookThresholdsMode)
+		}
+	default:
+		return nil, fmt.Errorf("unknown webhook mode %q, must be %s or %s", w.mode, WebhookSnapshotMode, WebhookThresholdsMode)
+	}
+	for i, t := range w.thresholds {
+		if _, err := path.Match(t.Metric, ""); err != nil || t.Metric == "" {
+			return nil, fmt.Errorf("threshold %d has an invalid metric pattern %q", i, t.Metric)
+		}
+		if t.Above == nil && t.Below == nil {
+			return nil, fmt.Errorf("threshold %d for %s needs above or below", i, t.Metric)
+		}
+		w.breached = append(w.breached, make(map[string]metrics.Metric))
+	}
+
+	if c.SecretFile != "" {
+		secret, err := os.ReadFile(c.SecretFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed reading webhook secret file: %w", err)
+		}
+		w.secret = bytes.TrimSpace(secret)
+	}
+
+	if w.source == "" {
+		w.source = defaultWebhookSource
+	}
+	if w.signatureHeader == "" {
+		w.signatureHeader = defaultWebhookSignatureHeader
+	}
+	if c.MaxRetries != nil {
+		w.maxRetries = *c.MaxRetries
+	}
+	if w.retryBackoff <= 0 {
+		w.retryBackoff = defaultWebhookRetryBackoff
+	}
+	if w.httpClient.Timeout <= 0 {
+		w.httpClient.Timeout = defaultWebhookTimeout
+	}
+	if w.flushTimeout <= 0 {
+		w.flushTimeout = defaultWebhookFlushTimeout
+	}
+
+	return w, nil
+}
+
+// cloudEvent is a CloudEvents 1.0 event in the structured JSON format.
+type cloudEvent struct {
+	SpecVersion     string      `json:"specversion"`
+	ID              string      `json:"id"`
+	Source          string      `json:"source"`
+	Type            string      `json:"type"`
+	Subject         string      `json:"subject,omitempty"`
+	Time            time.Time   `json:"time"`
+	DataContentType string      `json:"datacontenttype"`
+	Data            interface{} `json:"data"`
+}
+
+type snapshotEventData struct {
+	Metrics []metrics.Record `json:"metrics"`
+	// Failed lists the collectors, by org, which failed this cycle; their series are missing from Metrics.
+	Failed []metrics.Source `json:"failed"`
+}
+
+type thresholdEventData struct {
+	metrics.Record
+	Above *float64 `json:"above,omitempty"`
+	Below *float64 `json:"below,omitempty"`
+}
+
+func (w *Webhook) Name() string {
+	return "webhook"
+}
+
+func (w *Webhook) Write(ctx context.Context, snapshot *metrics.Snapshot) error {
+	ctx, cancel := context.WithTimeout(ctx, w.flushTimeout)
+	defer cancel()
+
+	if w.mode == WebhookThresholdsMode {
+		return w.writeCrossings(ctx, snapshot)
+	}
+
+	data := snapshotEventData{Metrics: []metrics.Record{}, Failed: append([]metrics.Source{}, snapshot.Failed()...)}
+	for _, m := range snapshot.Metrics() {
+		data.Metrics = append(data.Metrics, metrics.RecordOf(m))
+	}
+	return w.send(ctx, w.event(SnapshotEventType, "", snapshot.Time(), data))
+}
+
+// writeCrossings posts an event for every series which started or stopped breaching a threshold. Series missing
+// from the snapshot because their source failed keep their state, so a failed collector doesn't look like a
+// recovery; series which went away for good, such as deleted scans, are forgotten without an event. A crossing is
+// only considered sent once its event was accepted, so an event which failed is sent again next cycle if still true.
+func (w *Webhook) writeCrossings(ctx context.Context, snapshot *metrics.Snapshot) error {
+	present := make(map[string]bool)
+	var errs []error
+	for _, m := range snapshot.Metrics() {
+		present[m.Key()] = true
+		for i, t := range w.thresholds {
+			if !t.matches(m) {
+				continue
+			}
+
+			key := m.Key()
+			breached := t.breachedBy(m.Value)
+			if _, wasBreached := w.breached[i][key]; breached == wasBreached {
+				continue
+			}
+
+			eventType := ThresholdCrossedEventType
+			if !breached {
+				eventType = ThresholdRecoveredEventType
+			}
+			if ctx.Err() != nil {
+				errs = append(errs, fmt.Errorf("no time left to send %s for %s: %w", eventType, key, ctx.Err()))
+				continue
+			}
+			data := thresholdEventData{Record: metrics.RecordOf(m), Above: t.Above, Below: t.Below}
+			if err := w.send(ctx, w.event(eventType, key, snapshot.Time(), data)); err != nil {
+				errs = append(errs, fmt.Errorf("failed sending %s for %s: %w", eventType, key, err))
+				continue
+			}
+
+			if breached {
+				w.breached[i][key] = m
+			} else {
+				delete(w.breached[i], key)
+			}
+		}
+	}
+
+	failed := metrics.NewFailedSources(snapshot.Failed())
+	for _, breached := range w.breached {
+		for key, m := range breached {
+			if !present[key] && !failed.Covers(m) {
+				delete(breached, key)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (w *Webhook) event(eventType, subject string, at time.Time, data interface{}) cloudEvent {
+	return cloudEvent{
+		SpecVersion:     "1.0",
+		ID:              newEventID(),
+		Source:          w.source,
+		Type:            eventType,
+		Subject:         subject,
+		Time:            at.UTC(),
+		DataContentType: "application/json",
+		Data:            data,
+	}
+}
+
+func newEventID() string {
+	id := make([]byte, 16)
+	// crypto/rand only fails if the system's randomness source is broken.
+	_, _ = rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+// send posts a single event, retrying failures which might be temporary.
+func (w *Webhook) send(ctx context.Context, event cloudEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	return retry(ctx, "webhook delivery", w.maxRetries, w.retryBackoff, func() (bool, error) {
+		return w.post(ctx, body)
+	})
+}
+
+func (w *Webhook) post(ctx context.Context, body []byte) (retryable bool, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", cloudEventsContentType)
+	for k, v := range w.headers {
+		req.Header.Set(k, v)
+	}
+	if len(w.secret) > 0 {
+		req.Header.Set(w.signatureHeader, Signature(w.secret, body))
+	}
+
+	resp, err := w.httpClient.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode >= 200 && resp.StatusCode <= 299 {
+		return false, nil
+	}
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	retryable = resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	return retryable, fmt.Errorf("webhook returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+}
+
+// Signature is the value of the signature header for body: sha256= and the hex HMAC-SHA256 of body with secret.
+func Signature(secret, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func (w *Webhook) Close() error {
+	return nil
+}
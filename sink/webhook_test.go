@@ -0,0 +1,188 @@
+// Copyright 2022 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sink
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/palantir/tenablesc-metrics/metrics"
+)
+
+func TestWebhook_Write(t *testing.T) {
+	var mu sync.Mutex
+	var events []cloudEvent
+	var failNext bool
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		body, _ := io.ReadAll(r.Body)
+		if r.Header.Get("Content-Type") != cloudEventsContentType || r.Header.Get("X-Signature-256") != Signature([]byte("secret"), body) {
+			http.Error(w, "bad signature", http.StatusUnauthorized)
+			return
+		}
+		if failNext {
+			failNext = false
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		var event cloudEvent
+		_ = json.Unmarshal(body, &event)
+		events = append(events, event)
+	}))
+	defer server.Close()
+
+	scanAge := metrics.Source{Collector: "scanAge", Org: "foo"}
+	// A negative number of minutes leaves the scan out of the snapshot, because scanAge failed if failed is set.
+	stalenessOf := func(minutes float64, failed bool) *metrics.Snapshot {
+		ms := []metrics.Metric{metrics.NewMetric("failedUpdate", metrics.Gauge, 0, nil)}
+		if minutes >= 0 {
+			m := metrics.NewMetric("minutesSinceLastScan", metrics.Gauge, minutes, map[string]string{"scanName": "daily", metrics.OrgTagName: "foo"})
+			m.Collector, m.Org = scanAge.Collector, scanAge.Org
+			ms = append(ms, m)
+		}
+		var failedSources []metrics.Source
+		if failed {
+			failedSources = append(failedSources, scanAge)
+		}
+		return metrics.NewSnapshot(time.Unix(1700000000, 0), ms, failedSources)
+	}
+	staleness := func(minutes float64) *metrics.Snapshot {
+		return stalenessOf(minutes, false)
+	}
+
+	snapshots, err := NewWebhook(WebhookConfig{URL: server.URL, Secret: "secret", RetryBackoff: time.Millisecond})
+	if err != nil {
+		t.Fatalf("NewWebhook() error = %v", err)
+	}
+	failNext = true
+	if err := snapshots.Write(context.Background(), staleness(10)); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if len(events) != 1 || events[0].Type != SnapshotEventType || events[0].SpecVersion != "1.0" || len(events[0].Data.(map[string]interface{})["metrics"].([]interface{})) != 2 {
+		t.Fatalf("snapshot mode sent %+v, want a single snapshot event after one retry", events)
+	}
+
+	limit := 60.0
+	crossings, err := NewWebhook(WebhookConfig{
+		URL:        server.URL,
+		Secret:     "secret",
+		Mode:       WebhookThresholdsMode,
+		Thresholds: []WebhookThreshold{{Metric: "minutesSince*", Above: &limit}},
+		MaxRetries: new(int),
+	})
+	if err != nil {
+		t.Fatalf("NewWebhook() error = %v", err)
+	}
+
+	events = nil
+	for i, step := range []struct {
+		minutes      float64
+		sourceFailed bool
+		fail         bool
+		want         string
+	}{
+		{minutes: 10},
+		{minutes: 90, fail: true},
+		{minutes: 90, want: ThresholdCrossedEventType},
+		{minutes: 120},
+		{minutes: 30, want: ThresholdRecoveredEventType},
+		{minutes: 90, want: ThresholdCrossedEventType},
+		// scanAge failing doesn't make the breach look recovered.
+		{minutes: -1, sourceFailed: true},
+		{minutes: 90},
+		// The scan being deleted forgets the breach, so if it comes back breaching that is a new crossing.
+		{minutes: -1},
+		{minutes: 90, want: ThresholdCrossedEventType},
+	} {
+		failNext = step.fail
+		events = nil
+		err := crossings.Write(context.Background(), stalenessOf(step.minutes, step.sourceFailed))
+		if (err != nil) != step.fail {
+			t.Errorf("step %d: Write() error = %v", i, err)
+		}
+		if step.want == "" && len(events) != 0 {
+			t.Errorf("step %d: sent %+v, want nothing", i, events)
+		}
+		if step.want != "" && (len(events) != 1 || events[0].Type != step.want || events[0].Subject != "minutesSinceLastScan[org:foo,scanName:daily]") {
+			t.Errorf("step %d: sent %+v, want %s", i, events, step.want)
+		}
+	}
+}
+
+func TestWebhook_WriteFlushTimeout(t *testing.T) {
+	var mu sync.Mutex
+	var hang bool
+	var sent int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		hanging := hang
+		mu.Unlock()
+		if hanging {
+			// the request context is only cancelled once the body has been read.
+			_, _ = io.ReadAll(r.Body)
+			<-r.Context().Done()
+			return
+		}
+
+		mu.Lock()
+		sent++
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	limit := 60.0
+	crossings, err := NewWebhook(WebhookConfig{
+		URL:          server.URL,
+		Mode:         WebhookThresholdsMode,
+		Thresholds:   []WebhookThreshold{{Metric: "minutesSinceLastScan", Above: &limit}},
+		RetryBackoff: time.Millisecond,
+		FlushTimeout: 50 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("NewWebhook() error = %v", err)
+	}
+	var ms []metrics.Metric
+	for _, scan := range []string{"daily", "weekly", "monthly"} {
+		ms = append(ms, metrics.NewMetric("minutesSinceLastScan", metrics.Gauge, 90, map[string]string{"scanName": scan}))
+	}
+	snapshot := metrics.NewSnapshot(time.Unix(1700000000, 0), ms, nil)
+
+	// every event hangs, but the write as a whole gives up at the flush timeout.
+	hang = true
+	start := time.Now()
+	if err := crossings.Write(context.Background(), snapshot); err == nil {
+		t.Errorf("Write() to a hanging receiver succeeded")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Write() took %v, want it bounded by the flush timeout", elapsed)
+	}
+
+	// the crossings not sent in time are sent next cycle.
+	mu.Lock()
+	hang = false
+	mu.Unlock()
+	if err := crossings.Write(context.Background(), snapshot); err != nil || sent != 3 {
+		t.Errorf("Write() = %v after sending %d events, want 3", err, sent)
+	}
+}